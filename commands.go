@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	db "chirpy/internal/database"
+	"chirpy/internal/migrate"
+	"chirpy/internal/storage"
+	"github.com/pressly/goose/v3"
+)
+
+// runCommand dispatches a chirpy subcommand such as "chirpy bootstrap-admin".
+func runCommand(cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "bootstrap-admin":
+		return bootstrapAdminCommand(cfg, args)
+	case "migrate":
+		return migrateCommand(cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: bootstrap-admin, migrate)", name)
+	}
+}
+
+// migrateCommand applies or rolls back the embedded schema migrations:
+//
+//	chirpy migrate up      apply every pending migration
+//	chirpy migrate down    roll back the most recent migration
+//	chirpy migrate status  list migrations and when they were applied
+func migrateCommand(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: chirpy migrate up|down|status")
+	}
+
+	dbConn, err := storage.Open(cfg.Database.URL.Value(), cfg.Database.Pool())
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	provider, err := migrate.NewProvider(dbConn)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		logMigrationResults(results)
+		if err != nil {
+			return fmt.Errorf("migrate up: %w", err)
+		}
+		if len(results) == 0 {
+			log.Printf("database is up to date")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if result != nil {
+			logMigrationResults([]*goose.MigrationResult{result})
+		}
+		if err != nil {
+			return fmt.Errorf("migrate down: %w", err)
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate status: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tMIGRATION\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.State == goose.StateApplied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, filepath.Base(s.Source.Path), s.State, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate action %q (available: up, down, status)", args[0])
+	}
+	return nil
+}
+
+func logMigrationResults(results []*goose.MigrationResult) {
+	for _, r := range results {
+		log.Printf("migrated %s %s in %s", r.Direction, filepath.Base(r.Source.Path), r.Duration.Round(time.Millisecond))
+	}
+}
+
+// bootstrapAdminCommand promotes (or creates) the first admin account. It
+// refuses to run once an admin exists; further admins are managed through
+// PUT /admin/users/{userID}/role.
+func bootstrapAdminCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("bootstrap-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the account to promote or create")
+	password := fs.String("password", "", "password for a new account (default $CHIRPY_ADMIN_PASSWORD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *email == "" {
+		return errors.New("bootstrap-admin: -email is required")
+	}
+	if *password == "" {
+		*password = os.Getenv("CHIRPY_ADMIN_PASSWORD")
+	}
+
+	dbConn, err := storage.Open(cfg.Database.URL.Value(), cfg.Database.Pool())
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	dbUser, err := bootstrapAdmin(context.Background(), dbConn, *email, *password, cfg.Auth.Password.Policy())
+	if err != nil {
+		return fmt.Errorf("bootstrap-admin: %w", err)
+	}
+
+	log.Printf("user %s (%s) is now an admin", dbUser.Email, dbUser.ID)
+	return nil
+}
+
+func bootstrapAdmin(ctx context.Context, dbConn *storage.DB, email, password string, policy auth.PasswordPolicy) (db.User, error) {
+	var dbUser db.User
+	err := dbConn.InTx(ctx, func(queries db.Querier) error {
+		admins, err := queries.CountUsersByRole(ctx, string(auth.RoleAdmin))
+		if err != nil {
+			return err
+		}
+		if admins > 0 {
+			return errors.New("an admin already exists")
+		}
+
+		dbUser, err = queries.GetUserByEmail(ctx, email)
+		if errors.Is(err, db.ErrNotFound) {
+			if password == "" {
+				return errors.New("no account with that email; set -password or $CHIRPY_ADMIN_PASSWORD to create one")
+			}
+
+			if policyErr := policy.Check(password, email); policyErr != nil {
+				return policyErr
+			}
+
+			hashedPassword, hashErr := auth.HashPassword(password)
+			if hashErr != nil {
+				return hashErr
+			}
+
+			dbUser, err = queries.CreateUser(ctx, db.CreateUserParams{
+				Email:          email,
+				HashedPassword: hashedPassword,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		dbUser, err = queries.SetUserRole(ctx, db.SetUserRoleParams{
+			ID:   dbUser.ID,
+			Role: string(auth.RoleAdmin),
+		})
+		return err
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+
+	return dbUser, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"chirpy/internal/problem"
+)
+
+// maxRequestBodyBytes caps JSON request bodies. The largest request, a chirp
+// with a long body, is a few kilobytes.
+const maxRequestBodyBytes = 1 << 20
+
+// decodeJSON decodes the JSON object in r's body into dst and reports whether
+// it succeeded. Otherwise it has already responded with a problem: 415 when
+// the body is not declared as JSON, 413 when it exceeds
+// maxRequestBodyBytes, and 400 when it is not exactly one JSON object whose
+// fields all fit dst, naming the offending field where there is one.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		problem.Write(w, r, problem.UnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	} else if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		problem.Write(w, r, problem.UnsupportedMediaType, "Request body must be UTF-8")
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err == nil {
+		// A second value, or anything but whitespace, after the object.
+		if decoder.Decode(&struct{}{}) != io.EOF {
+			problem.Write(w, r, problem.InvalidBody, "Request body must contain a single JSON object")
+			return false
+		}
+		return true
+	}
+
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		problem.Write(w, r, problem.PayloadTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		problem.Write(w, r, problem.InvalidBody, "Request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		problem.Write(w, r, problem.InvalidBody, "Request body ends before the JSON is complete")
+	case errors.As(err, &syntaxErr):
+		problem.Write(w, r, problem.InvalidBody, fmt.Sprintf("Malformed JSON at byte %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		problem.Write(w, r, problem.InvalidBody, "Request body must be a JSON object")
+	case errors.As(err, &typeErr):
+		detail := fmt.Sprintf("%s must be %s, not %s", typeErr.Field, jsonKind(typeErr.Type), typeErr.Value)
+		problem.Write(w, r, problem.InvalidBody, detail, problem.FieldError{Field: typeErr.Field, Code: problem.Invalid, Detail: detail})
+	default:
+		// encoding/json reports unknown fields with an untyped error.
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			if name, err := strconv.Unquote(field); err == nil {
+				field = name
+			}
+			detail := fmt.Sprintf("Unknown field %q", field)
+			problem.Write(w, r, problem.InvalidBody, detail, problem.FieldError{Field: field, Code: problem.Unknown, Detail: detail})
+			return false
+		}
+		problem.Write(w, r, problem.InvalidBody, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return false
+}
+
+// jsonKind names the JSON value that decodes into t.
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
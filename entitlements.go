@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"chirpy/internal/auth"
+	db "chirpy/internal/database"
+	"chirpy/internal/entitlements"
+	"chirpy/internal/problem"
+	"github.com/google/uuid"
+)
+
+// Entitlements is the JSON representation of the features an account may use.
+type Entitlements struct {
+	UserID        uuid.UUID              `json:"user_id"`
+	Plan          entitlements.Plan      `json:"plan"`
+	Features      []entitlements.Feature `json:"features"`
+	ChirpLength   int                    `json:"chirp_length_limit"`
+	ChirpsPerHour int                    `json:"chirps_per_hour"`
+	Overrides     []EntitlementOverride  `json:"overrides,omitempty"`
+}
+
+// EntitlementOverride is an administrator's grant or revocation of a feature.
+type EntitlementOverride struct {
+	Feature   string     `json:"feature"`
+	Granted   bool       `json:"granted"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// entitlementsFor resolves userID's features from their plan and any
+// administrator overrides.
+func (cfg *apiConfig) entitlementsFor(ctx context.Context, userID uuid.UUID) (entitlements.Set, []db.UserEntitlement, error) {
+	isChirpyRed, err := cfg.isChirpyRed(ctx, userID)
+	if err != nil {
+		return entitlements.Set{}, nil, err
+	}
+	plan := entitlements.PlanFree
+	if isChirpyRed {
+		plan = entitlements.PlanRed
+	}
+
+	rows, err := cfg.dbQueries.ListUserEntitlements(ctx, userID)
+	if err != nil {
+		return entitlements.Set{}, nil, err
+	}
+	overrides := make([]entitlements.Override, 0, len(rows))
+	for _, row := range rows {
+		overrides = append(overrides, entitlements.Override{
+			Feature: entitlements.Feature(row.Feature),
+			Granted: row.Granted,
+		})
+	}
+
+	return entitlements.Resolve(plan, overrides), rows, nil
+}
+
+func entitlementsResponse(userID uuid.UUID, set entitlements.Set, rows []db.UserEntitlement) Entitlements {
+	resp := Entitlements{
+		UserID:        userID,
+		Plan:          set.Plan,
+		Features:      set.List(),
+		ChirpLength:   set.ChirpLength(),
+		ChirpsPerHour: set.ChirpsPerHour(),
+	}
+	for _, row := range rows {
+		override := EntitlementOverride{
+			Feature:   row.Feature,
+			Granted:   row.Granted,
+			UpdatedAt: row.UpdatedAt,
+		}
+		if row.GrantedBy.Valid {
+			override.GrantedBy = &row.GrantedBy.UUID
+		}
+		resp.Overrides = append(resp.Overrides, override)
+	}
+	return resp
+}
+
+// myEntitlementsHandler returns the caller's own plan, features and limits.
+func (cfg *apiConfig) myEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	set, _, err := cfg.entitlementsFor(r.Context(), principal.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading entitlements", "user_id", principal.UserID, "error", err)
+		respondWithDBError(w, r, err, "Could not retrieve entitlements")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entitlementsResponse(principal.UserID, set, nil))
+}
+
+// getUserEntitlementsHandler returns a user's entitlements along with the
+// overrides administrators have applied.
+func (cfg *apiConfig) getUserEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if _, err := cfg.dbQueries.GetUser(r.Context(), userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving user", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not retrieve entitlements")
+		return
+	}
+
+	cfg.respondWithUserEntitlements(w, r, userID)
+}
+
+// setUserEntitlementHandler grants or revokes a single feature for a user,
+// overriding whatever their plan includes.
+func (cfg *apiConfig) setUserEntitlementHandler(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Granted *bool `json:"granted"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	feature, err := entitlements.ParseFeature(r.PathValue("feature"))
+	if err != nil {
+		respondWithInvalidField(w, r, "feature", problem.Unknown, "Unknown feature")
+		return
+	}
+
+	var params requestBody
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if params.Granted == nil {
+		respondWithInvalidField(w, r, "granted", problem.Required, "granted is required")
+		return
+	}
+
+	_, err = cfg.dbQueries.UpsertUserEntitlement(r.Context(), db.UpsertUserEntitlementParams{
+		UserID:    userID,
+		Feature:   string(feature),
+		Granted:   *params.Granted,
+		GrantedBy: uuid.NullUUID{UUID: principal.UserID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "setting entitlement", "feature", feature, "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update entitlements")
+		return
+	}
+
+	cfg.respondWithUserEntitlements(w, r, userID)
+}
+
+// deleteUserEntitlementHandler removes an override so the user falls back to
+// what their plan includes.
+func (cfg *apiConfig) deleteUserEntitlementHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	feature, err := entitlements.ParseFeature(r.PathValue("feature"))
+	if err != nil {
+		respondWithInvalidField(w, r, "feature", problem.Unknown, "Unknown feature")
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteUserEntitlement(r.Context(), db.DeleteUserEntitlementParams{
+		UserID:  userID,
+		Feature: string(feature),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "deleting entitlement", "feature", feature, "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update entitlements")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, r, http.StatusNotFound, "Override not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) respondWithUserEntitlements(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	set, rows, err := cfg.entitlementsFor(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading entitlements", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not retrieve entitlements")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entitlementsResponse(userID, set, rows))
+}
@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -13,9 +15,73 @@ import (
 	"github.com/google/uuid"
 )
 
+// PasswordParams are the tunable Argon2id cost settings.
+type PasswordParams struct {
+	// Memory is the amount of memory used by the algorithm, in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of threads used by the algorithm.
+	Parallelism uint8
+}
+
+// DefaultPasswordParams mirrors argon2id.DefaultParams.
+var DefaultPasswordParams = PasswordParams{
+	Memory:      argon2id.DefaultParams.Memory,
+	Iterations:  argon2id.DefaultParams.Iterations,
+	Parallelism: argon2id.DefaultParams.Parallelism,
+}
+
+// Validate reports whether the parameters are usable by Argon2id.
+func (p PasswordParams) Validate() error {
+	if p.Iterations < 1 {
+		return errors.New("argon2id iterations must be at least 1")
+	}
+	if p.Parallelism < 1 {
+		return errors.New("argon2id parallelism must be at least 1")
+	}
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("argon2id memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	return nil
+}
+
+func (p PasswordParams) argon2id() *argon2id.Params {
+	return &argon2id.Params{
+		Memory:      p.Memory,
+		Iterations:  p.Iterations,
+		Parallelism: p.Parallelism,
+		SaltLength:  argon2id.DefaultParams.SaltLength,
+		KeyLength:   argon2id.DefaultParams.KeyLength,
+	}
+}
+
+var (
+	passwordParamsMu sync.RWMutex
+	passwordParams   = DefaultPasswordParams
+)
+
+// SetPasswordParams changes the parameters used by HashPassword. It is meant
+// to be called once during startup.
+func SetPasswordParams(p PasswordParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	passwordParamsMu.Lock()
+	defer passwordParamsMu.Unlock()
+	passwordParams = p
+	return nil
+}
+
+func currentPasswordParams() PasswordParams {
+	passwordParamsMu.RLock()
+	defer passwordParamsMu.RUnlock()
+	return passwordParams
+}
+
 // HashPassword hashes the provided plaintext password using Argon2id.
 func HashPassword(password string) (string, error) {
-	return argon2id.CreateHash(password, argon2id.DefaultParams)
+	return argon2id.CreateHash(password, currentPasswordParams().argon2id())
 }
 
 // CheckPasswordHash compares a plaintext password to a stored Argon2id hash.
@@ -23,25 +89,88 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return argon2id.ComparePasswordAndHash(password, hash)
 }
 
+// NeedsRehash reports whether hash was created with a lower memory or
+// iteration cost, or a shorter salt or key, than HashPassword currently uses.
+// Parallelism is ignored because it tracks the hashing machine's CPU count
+// rather than the strength of the hash.
+func NeedsRehash(hash string) (bool, error) {
+	stored, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	want := currentPasswordParams().argon2id()
+	return stored.Memory < want.Memory ||
+		stored.Iterations < want.Iterations ||
+		stored.SaltLength < want.SaltLength ||
+		stored.KeyLength < want.KeyLength, nil
+}
+
+// Token types carried in the typ claim of Chirpy access tokens.
+const (
+	// TokenTypeAccess identifies a first-party access token issued by MakeJWT.
+	TokenTypeAccess = "access"
+	// TokenTypeOAuthAccess identifies an access token issued to a third-party
+	// OAuth client on behalf of a user.
+	TokenTypeOAuthAccess = "oauth_access"
+)
+
+// Claims are the JWT claims carried by Chirpy access tokens.
+type Claims struct {
+	jwt.RegisteredClaims
+	TokenType string `json:"typ,omitempty"`
+	Role      Role   `json:"role,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+}
+
+// TokenOptions carries the optional claims accepted by MakeJWTWithOptions.
+type TokenOptions struct {
+	Role   Role
+	Scopes []string
+	// ClientID marks the token as issued to a third-party OAuth client.
+	ClientID string
+}
+
+// Scopes returns the space-delimited scope claim as a slice.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
 // MakeJWT creates a signed JWT for the given user ID with the provided secret and expiration.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return MakeJWTWithOptions(userID, tokenSecret, expiresIn, TokenOptions{})
+}
+
+// MakeJWTWithOptions creates a signed JWT like MakeJWT, additionally embedding
+// a role and scopes. Tokens with a ClientID are typed as OAuth access tokens.
+func MakeJWTWithOptions(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, opts TokenOptions) (string, error) {
 	issuedAt := time.Now().UTC()
 	expiresAt := issuedAt.Add(expiresIn)
 
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		Subject:   userID.String(),
-		IssuedAt:  jwt.NewNumericDate(issuedAt),
-		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		TokenType: TokenTypeAccess,
+		Role:      opts.Role,
+		Scope:     strings.Join(opts.Scopes, " "),
+		ClientID:  opts.ClientID,
+	}
+	if opts.ClientID != "" {
+		claims.TokenType = TokenTypeOAuthAccess
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(tokenSecret))
 }
 
-// ValidateJWT verifies the provided token string and returns the embedded user ID if valid.
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+// ParseJWT verifies the provided token string and returns its claims if valid.
+func ParseJWT(tokenString, tokenSecret string) (*Claims, error) {
+	claims := &Claims{}
 
 	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS256 {
@@ -50,11 +179,28 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	if !parsedToken.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+
+	if claims.TokenType == "" {
+		claims.TokenType = TokenTypeAccess
+	}
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+
+	return claims, nil
+}
+
+// ValidateJWT verifies the provided token string and returns the embedded user ID if valid.
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
 	userID, err := uuid.Parse(claims.Subject)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -187,3 +188,132 @@ func TestGetAPIKeyErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeJWTWithOptions(t *testing.T) {
+	userID := uuid.New()
+	secret := "test-secret"
+
+	token, err := MakeJWTWithOptions(userID, secret, time.Minute, TokenOptions{
+		Role:   RoleAdmin,
+		Scopes: []string{"chirps:read", "chirps:write"},
+	})
+	if err != nil {
+		t.Fatalf("MakeJWTWithOptions() error = %v", err)
+	}
+
+	claims, err := ParseJWT(token, secret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+
+	if claims.Role != RoleAdmin {
+		t.Fatalf("ParseJWT() role = %q, want %q", claims.Role, RoleAdmin)
+	}
+	if got := claims.Scopes(); len(got) != 2 || got[0] != "chirps:read" || got[1] != "chirps:write" {
+		t.Fatalf("ParseJWT() scopes = %v", got)
+	}
+}
+
+func TestParseJWTDefaultsRole(t *testing.T) {
+	secret := "test-secret"
+	token, err := MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	claims, err := ParseJWT(token, secret)
+	if err != nil {
+		t.Fatalf("ParseJWT() error = %v", err)
+	}
+
+	if claims.Role != RoleUser {
+		t.Fatalf("ParseJWT() role = %q, want %q", claims.Role, RoleUser)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := PasswordParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+	strong := PasswordParams{Memory: 16 * 1024, Iterations: 2, Parallelism: 1}
+	t.Cleanup(func() {
+		_ = SetPasswordParams(DefaultPasswordParams)
+	})
+
+	if err := SetPasswordParams(weak); err != nil {
+		t.Fatalf("SetPasswordParams() error = %v", err)
+	}
+	hash, err := HashPassword("supersafe")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	needsRehash, err := NeedsRehash(hash)
+	if err != nil {
+		t.Fatalf("NeedsRehash() error = %v", err)
+	}
+	if needsRehash {
+		t.Fatalf("NeedsRehash() = true for hash created with current params")
+	}
+
+	if err := SetPasswordParams(strong); err != nil {
+		t.Fatalf("SetPasswordParams() error = %v", err)
+	}
+	needsRehash, err = NeedsRehash(hash)
+	if err != nil {
+		t.Fatalf("NeedsRehash() error = %v", err)
+	}
+	if !needsRehash {
+		t.Fatalf("NeedsRehash() = false for hash created with weaker params")
+	}
+
+	match, err := CheckPasswordHash("supersafe", hash)
+	if err != nil || !match {
+		t.Fatalf("CheckPasswordHash() = %v, %v; old hashes must still verify", match, err)
+	}
+}
+
+func TestSetPasswordParamsRejectsInvalid(t *testing.T) {
+	cases := []PasswordParams{
+		{Memory: 64 * 1024, Iterations: 0, Parallelism: 1},
+		{Memory: 64 * 1024, Iterations: 1, Parallelism: 0},
+		{Memory: 8, Iterations: 1, Parallelism: 4},
+	}
+
+	for _, params := range cases {
+		if err := SetPasswordParams(params); err == nil {
+			t.Errorf("SetPasswordParams(%+v) expected error", params)
+		}
+	}
+}
+
+// BenchmarkHashPassword helps choose ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and
+// ARGON2_PARALLELISM for a given machine. Aim for the strongest setting that
+// keeps a single hash well under the login latency budget, e.g.
+//
+//	go test ./internal/auth -run '^$' -bench HashPassword -benchmem
+func BenchmarkHashPassword(b *testing.B) {
+	memories := []uint32{19 * 1024, 46 * 1024, 64 * 1024, 128 * 1024}
+	iterations := []uint32{1, 2, 3}
+	parallelisms := []uint8{1, 2, 4}
+
+	b.Cleanup(func() {
+		_ = SetPasswordParams(DefaultPasswordParams)
+	})
+
+	for _, m := range memories {
+		for _, it := range iterations {
+			for _, p := range parallelisms {
+				params := PasswordParams{Memory: m, Iterations: it, Parallelism: p}
+				b.Run(fmt.Sprintf("m=%dMiB/t=%d/p=%d", m/1024, it, p), func(b *testing.B) {
+					if err := SetPasswordParams(params); err != nil {
+						b.Fatalf("SetPasswordParams() error = %v", err)
+					}
+					for i := 0; i < b.N; i++ {
+						if _, err := HashPassword("benchmark-password"); err != nil {
+							b.Fatalf("HashPassword() error = %v", err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LockoutPolicy describes how repeated login failures are throttled.
+// The first Threshold-1 failures within Window are free; each failure from
+// the Threshold-th onward locks the key for BaseDelay, doubling every time
+// up to MaxDelay.
+type LockoutPolicy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+// DefaultAccountLockout throttles guesses against a single email address.
+var DefaultAccountLockout = LockoutPolicy{
+	Threshold: 5,
+	BaseDelay: 30 * time.Second,
+	MaxDelay:  15 * time.Minute,
+	Window:    time.Hour,
+}
+
+// DefaultIPLockout throttles guesses from a single client address, which may
+// legitimately be shared by several users.
+var DefaultIPLockout = LockoutPolicy{
+	Threshold: 20,
+	BaseDelay: 30 * time.Second,
+	MaxDelay:  time.Hour,
+	Window:    time.Hour,
+}
+
+// LockoutFor returns how long a key should be locked after the given number
+// of consecutive failures, or zero if it should not be locked.
+func (p LockoutPolicy) LockoutFor(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// CompareDummyPassword performs an Argon2id comparison against a throwaway
+// hash. Call it when a login names an unknown account so the response takes
+// as long as it would for a wrong password.
+func CompareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		hash, err := HashPassword("chirpy-dummy-password")
+		if err == nil {
+			dummyHash = hash
+		}
+	})
+	if dummyHash == "" {
+		return
+	}
+	_, _ = CheckPasswordHash(password, dummyHash)
+}
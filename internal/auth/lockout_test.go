@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyLockoutFor(t *testing.T) {
+	policy := LockoutPolicy{
+		Threshold: 3,
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+		Window:    time.Hour,
+	}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Second},
+		{failures: 4, want: 2 * time.Second},
+		{failures: 5, want: 4 * time.Second},
+		{failures: 6, want: 8 * time.Second},
+		{failures: 7, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+
+	for _, tc := range cases {
+		if got := policy.LockoutFor(tc.failures); got != tc.want {
+			t.Errorf("LockoutFor(%d) = %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestLockoutPolicyDisabled(t *testing.T) {
+	if got := (LockoutPolicy{}).LockoutFor(1000); got != 0 {
+		t.Fatalf("LockoutFor() with zero policy = %v, want 0", got)
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/problem"
+	"github.com/google/uuid"
+)
+
+// ErrPrincipalNotFound is returned by a PrincipalLoader when the token subject no longer exists.
+var ErrPrincipalNotFound = errors.New("principal not found")
+
+// Principal describes the authenticated caller of a request.
+type Principal struct {
+	UserID      uuid.UUID
+	TokenType   string
+	Role        Role
+	Scopes      []string
+	ClientID    string
+	IsChirpyRed bool
+}
+
+// IsFirstParty reports whether the principal authenticated with a token issued
+// by Chirpy itself rather than to a third-party OAuth client.
+func (p *Principal) IsFirstParty() bool {
+	return p.TokenType == TokenTypeAccess && p.ClientID == ""
+}
+
+// HasScope reports whether the principal was granted the given scope.
+// First-party tokens without a scope claim are treated as unrestricted.
+func (p *Principal) HasScope(scope string) bool {
+	if p.IsFirstParty() && len(p.Scopes) == 0 {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the given principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// PrincipalLoader fills in account details, such as role and Chirpy Red
+// status, for a principal whose token has already been verified. It should return
+// ErrPrincipalNotFound if the user no longer exists.
+type PrincipalLoader func(ctx context.Context, p *Principal) error
+
+// Authenticator resolves bearer tokens into a Principal stored on the request context.
+type Authenticator struct {
+	secret string
+	load   PrincipalLoader
+	realm  string
+}
+
+// NewAuthenticator creates an Authenticator that validates JWTs signed with secret.
+// load may be nil if no account lookup is required.
+func NewAuthenticator(secret string, load PrincipalLoader) *Authenticator {
+	return &Authenticator{
+		secret: secret,
+		load:   load,
+		realm:  "chirpy",
+	}
+}
+
+// Required rejects requests without a valid bearer token or session cookie
+// with 401 Unauthorized.
+func (a *Authenticator) Required(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasCredentials(r) {
+			a.unauthorized(w, r, "")
+			return
+		}
+		a.serveAuthenticated(w, r, next)
+	})
+}
+
+// Optional resolves the principal when an Authorization header or session
+// cookie is present and passes anonymous requests through unchanged. A malformed or invalid token is
+// still rejected so that clients notice expired credentials.
+func (a *Authenticator) Optional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasCredentials(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		a.serveAuthenticated(w, r, next)
+	})
+}
+
+// RequireScopes rejects requests whose principal lacks any of the given scopes
+// with 403 Forbidden. It must be wrapped by Required.
+func (a *Authenticator) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				a.unauthorized(w, r, "")
+				return
+			}
+			for _, scope := range scopes {
+				if !p.HasScope(scope) {
+					a.forbidden(w, r, "insufficient_scope", strings.Join(scopes, " "))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Authenticator) serveAuthenticated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	p, err := a.authenticate(r)
+	if err != nil {
+		var loadErr *principalLoadError
+		switch {
+		case errors.Is(err, errMalformedHeader):
+			a.unauthorized(w, r, "invalid_request")
+		case errors.Is(err, ErrInvalidCSRFToken):
+			problem.Write(w, r, ProblemInvalidCSRF, "")
+		case errors.As(err, &loadErr) && !errors.Is(err, ErrPrincipalNotFound):
+			slog.ErrorContext(r.Context(), "loading principal", "error", loadErr.err)
+			problem.Write(w, r, problem.Internal, "Could not authenticate request")
+		default:
+			a.unauthorized(w, r, "invalid_token")
+		}
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+}
+
+var errMalformedHeader = errors.New("malformed authorization header")
+
+type principalLoadError struct {
+	err error
+}
+
+func (e *principalLoadError) Error() string { return e.err.Error() }
+
+func (e *principalLoadError) Unwrap() error { return e.err }
+
+// hasCredentials reports whether the request carries a bearer token or an
+// access token cookie.
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	cookie, err := r.Cookie(AccessTokenCookie)
+	return err == nil && cookie.Value != ""
+}
+
+// accessToken returns the bearer token, falling back to the session cookie.
+// Cookie-authenticated requests must pass the double-submit CSRF check.
+func accessToken(r *http.Request) (string, error) {
+	if r.Header.Get("Authorization") != "" {
+		token, err := GetBearerToken(r.Header)
+		if err != nil {
+			return "", errMalformedHeader
+		}
+		return token, nil
+	}
+
+	cookie, err := r.Cookie(AccessTokenCookie)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckCSRF(r); err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	token, err := accessToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := ParseJWT(token, a.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Principal{
+		UserID:    userID,
+		TokenType: claims.TokenType,
+		Role:      claims.Role,
+		Scopes:    claims.Scopes(),
+		ClientID:  claims.ClientID,
+	}
+
+	if a.load != nil {
+		if err := a.load(r.Context(), p); err != nil {
+			return nil, &principalLoadError{err: err}
+		}
+	}
+
+	// Third-party clients act with the user's data but never their privileges.
+	if !p.IsFirstParty() {
+		p.Role = RoleUser
+	}
+
+	return p, nil
+}
+
+// ProblemInvalidCSRF rejects a cookie-authenticated request whose CSRF token
+// is missing or does not match the session's.
+var ProblemInvalidCSRF = problem.Type{Code: "invalid_csrf_token", Status: http.StatusForbidden, Title: "Invalid CSRF token"}
+
+// authProblems maps the RFC 6750 error codes sent in WWW-Authenticate to the
+// matching problem types.
+var authProblems = map[string]problem.Type{
+	"":                   problem.Unauthorized,
+	"invalid_request":    {Code: "malformed_credentials", Status: http.StatusUnauthorized, Title: "Malformed credentials"},
+	"invalid_token":      {Code: "invalid_token", Status: http.StatusUnauthorized, Title: "Invalid or expired credentials"},
+	"insufficient_scope": {Code: "insufficient_scope", Status: http.StatusForbidden, Title: "Insufficient scope"},
+	"insufficient_role":  {Code: "insufficient_role", Status: http.StatusForbidden, Title: "Insufficient role"},
+}
+
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request, code string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", a.realm)
+	if code != "" {
+		challenge += fmt.Sprintf(", error=%q", code)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	problem.Write(w, r, authProblems[code], "")
+}
+
+func (a *Authenticator) forbidden(w http.ResponseWriter, r *http.Request, code, scope string) {
+	challenge := fmt.Sprintf("Bearer realm=%q, error=%q", a.realm, code)
+	detail := ""
+	if scope != "" {
+		challenge += fmt.Sprintf(", scope=%q", scope)
+		detail = "Requires scope " + scope
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	problem.Write(w, r, authProblems[code], detail)
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAuthenticatorRequired(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	token, err := MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	authn := NewAuthenticator(secret, func(ctx context.Context, p *Principal) error {
+		p.IsChirpyRed = true
+		return nil
+	})
+
+	var got *Principal
+	handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got == nil {
+		t.Fatalf("expected principal in context")
+	}
+	if got.UserID != userID || got.TokenType != TokenTypeAccess || !got.IsChirpyRed {
+		t.Fatalf("principal = %+v, want user %s access token with chirpy red", got, userID)
+	}
+}
+
+func TestAuthenticatorRequiredRejects(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	valid, err := MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	expired, err := MakeJWT(userID, secret, -time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		header    string
+		load      PrincipalLoader
+		status    int
+		challenge string
+	}{
+		{
+			name:      "missing header",
+			status:    http.StatusUnauthorized,
+			challenge: `Bearer realm="chirpy"`,
+		},
+		{
+			name:      "wrong scheme",
+			header:    "ApiKey abc",
+			status:    http.StatusUnauthorized,
+			challenge: `error="invalid_request"`,
+		},
+		{
+			name:      "expired token",
+			header:    "Bearer " + expired,
+			status:    http.StatusUnauthorized,
+			challenge: `error="invalid_token"`,
+		},
+		{
+			name:   "deleted user",
+			header: "Bearer " + valid,
+			load: func(ctx context.Context, p *Principal) error {
+				return ErrPrincipalNotFound
+			},
+			status:    http.StatusUnauthorized,
+			challenge: `error="invalid_token"`,
+		},
+		{
+			name:   "loader failure",
+			header: "Bearer " + valid,
+			load: func(ctx context.Context, p *Principal) error {
+				return errors.New("database unavailable")
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			authn := NewAuthenticator(secret, tc.load)
+			handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Fatalf("handler should not be called")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, tc.challenge) {
+				t.Fatalf("WWW-Authenticate = %q, want it to contain %q", got, tc.challenge)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorOptional(t *testing.T) {
+	authn := NewAuthenticator("test-secret", nil)
+
+	var called, authenticated bool
+	handler := authn.Optional(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		_, authenticated = PrincipalFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !called {
+		t.Fatalf("expected anonymous request to reach handler")
+	}
+	if authenticated {
+		t.Fatalf("expected no principal for anonymous request")
+	}
+}
+
+func TestAuthenticatorRequireScopes(t *testing.T) {
+	authn := NewAuthenticator("test-secret", nil)
+	handler := authn.RequireScopes("chirps:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req = req.WithContext(WithPrincipal(req.Context(), &Principal{
+		UserID: uuid.New(),
+		Scopes: []string{"chirps:read"},
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_scope"`) {
+		t.Fatalf("WWW-Authenticate = %q, want insufficient_scope", got)
+	}
+}
+
+func TestAuthenticatorThirdPartyTokens(t *testing.T) {
+	secret := "test-secret"
+	token, err := MakeJWTWithOptions(uuid.New(), secret, time.Minute, TokenOptions{
+		Scopes:   []string{ScopeChirpsWrite},
+		ClientID: uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("MakeJWTWithOptions() error = %v", err)
+	}
+
+	authn := NewAuthenticator(secret, func(ctx context.Context, p *Principal) error {
+		p.Role = RoleAdmin
+		return nil
+	})
+
+	var got *Principal
+	handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent || got == nil {
+		t.Fatalf("status = %d, want %d with principal", rec.Code, http.StatusNoContent)
+	}
+	if got.IsFirstParty() || got.TokenType != TokenTypeOAuthAccess {
+		t.Fatalf("principal = %+v, want third-party OAuth token", got)
+	}
+	if got.Role != RoleUser {
+		t.Fatalf("Role = %q, want %q for third-party token", got.Role, RoleUser)
+	}
+	if !got.HasScope(ScopeChirpsWrite) || got.HasScope(ScopeAccount) {
+		t.Fatalf("scopes = %v, want only %s", got.Scopes, ScopeChirpsWrite)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOAuthScopes(t *testing.T) {
+	got, err := ParseOAuthScopes("chirps:write  chirps:write")
+	if err != nil {
+		t.Fatalf("ParseOAuthScopes() error = %v", err)
+	}
+	if want := []string{ScopeChirpsWrite}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseOAuthScopes() = %v, want %v", got, want)
+	}
+
+	for _, scope := range []string{ScopeAccount, "chirps:read", "chirps:write admin"} {
+		if _, err := ParseOAuthScopes(scope); err == nil {
+			t.Errorf("ParseOAuthScopes(%q) error = nil, want error", scope)
+		}
+	}
+}
+
+func TestScopesSubset(t *testing.T) {
+	allowed := []string{"a", "b"}
+	if !ScopesSubset([]string{"b"}, allowed) || !ScopesSubset(nil, allowed) {
+		t.Fatalf("ScopesSubset() = false for a subset")
+	}
+	if ScopesSubset([]string{"a", "c"}, allowed) {
+		t.Fatalf("ScopesSubset() = true for a superset")
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	cases := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{name: "match", verifier: verifier, challenge: challenge, want: true},
+		{name: "wrong verifier", verifier: strings.Repeat("w", 43), challenge: challenge},
+		{name: "plain challenge", verifier: verifier, challenge: verifier},
+		{name: "too short", verifier: "short", challenge: "short"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := VerifyPKCE(tc.verifier, tc.challenge); got != tc.want {
+				t.Fatalf("VerifyPKCE() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckTokenHash(t *testing.T) {
+	hash := HashToken("secret")
+	if !CheckTokenHash("secret", hash) {
+		t.Fatalf("CheckTokenHash() = false for matching secret")
+	}
+	if CheckTokenHash("Secret", hash) {
+		t.Fatalf("CheckTokenHash() = true for different secret")
+	}
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	cases := map[string]bool{
+		"https://app.example.com/callback": true,
+		"http://localhost:3000/cb":         true,
+		"https://app.example.com/cb#frag":  false,
+		"javascript:alert(1)":              false,
+		"/relative":                        false,
+		"":                                 false,
+	}
+	for uri, want := range cases {
+		if got := ValidRedirectURI(uri); got != want {
+			t.Errorf("ValidRedirectURI(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy rule identifiers reported in PasswordViolation.Rule.
+const (
+	PasswordRuleMinLength     = "min_length"
+	PasswordRuleMaxLength     = "max_length"
+	PasswordRuleMinEntropy    = "min_entropy"
+	PasswordRuleBreached      = "breached"
+	PasswordRuleContainsEmail = "contains_email"
+)
+
+// MaxPasswordLength is the longest password, in characters, that Check
+// accepts. It bounds the work EstimatePasswordEntropy does per request.
+const MaxPasswordLength = 128
+
+// PasswordViolation describes one password policy rule that was not met.
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password violated.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "password does not meet policy: " + strings.Join(messages, "; ")
+}
+
+// PasswordPolicy configures the checks applied to new passwords.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters. Zero disables the check.
+	MinLength int
+	// MinEntropyBits is the minimum estimated guessing entropy, see
+	// EstimatePasswordEntropy. Zero disables the check.
+	MinEntropyBits float64
+	// RejectBreached rejects passwords from the bundled breached-password list.
+	RejectBreached bool
+	// RejectEmail rejects passwords containing the account's email address
+	// or its local part.
+	RejectEmail bool
+}
+
+// DefaultPasswordPolicy is applied when no policy is configured.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      8,
+	MinEntropyBits: 28,
+	RejectBreached: true,
+	RejectEmail:    true,
+}
+
+// Check validates password against the policy for the account with the given
+// email. It returns a *PasswordPolicyError listing every violated rule, or nil.
+// A password longer than MaxPasswordLength is rejected without running the
+// other checks.
+func (p PasswordPolicy) Check(password, email string) error {
+	if utf8.RuneCountInString(password) > MaxPasswordLength {
+		return &PasswordPolicyError{Violations: []PasswordViolation{{
+			Rule:    PasswordRuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d characters long", MaxPasswordLength),
+		}}}
+	}
+
+	var violations []PasswordViolation
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if p.RejectBreached && IsBreachedPassword(password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleBreached,
+			Message: "appears in a list of breached passwords",
+		})
+	}
+
+	if p.RejectEmail && containsEmail(password, email) {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleContainsEmail,
+			Message: "must not contain your email address",
+		})
+	}
+
+	if p.MinEntropyBits > 0 && EstimatePasswordEntropy(password) < p.MinEntropyBits {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleMinEntropy,
+			Message: "is too easy to guess; try a longer phrase of unrelated words",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsEmail(password, email string) bool {
+	password = strings.ToLower(password)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	if strings.Contains(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(password, local)
+}
+
+//go:embed breached_passwords.txt
+var breachedPasswordsFile string
+
+var (
+	breachedOnce  sync.Once
+	breachedRanks map[string]int
+	// maxPatternLength is the longest run of characters patternEntropy can
+	// match as a dictionary word or keyboard run.
+	maxPatternLength int
+)
+
+// breachedPasswordRanks returns the bundled list as a map from lowercase
+// password to its 1-based frequency rank.
+func breachedPasswordRanks() map[string]int {
+	breachedOnce.Do(func() {
+		breachedRanks = make(map[string]int)
+		for _, row := range keyboardRows {
+			maxPatternLength = max(maxPatternLength, utf8.RuneCountInString(row))
+		}
+		scanner := bufio.NewScanner(strings.NewReader(breachedPasswordsFile))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, ok := breachedRanks[line]; !ok {
+				breachedRanks[line] = len(breachedRanks) + 1
+				maxPatternLength = max(maxPatternLength, utf8.RuneCountInString(line))
+			}
+		}
+	})
+	return breachedRanks
+}
+
+// IsBreachedPassword reports whether password, ignoring case, appears in the
+// bundled breached-password list.
+func IsBreachedPassword(password string) bool {
+	_, ok := breachedPasswordRanks()[strings.ToLower(password)]
+	return ok
+}
+
+var leetSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i',
+	'!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't',
+	'2': 'z',
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// EstimatePasswordEntropy returns a zxcvbn-style estimate, in bits, of how
+// many guesses an attacker needs. The password is split into the cheapest
+// sequence of dictionary words (including l33t and capitalised variants),
+// repeats, character sequences, keyboard runs and brute-forced characters.
+//
+// Candidate patterns are no longer than the longest dictionary word or
+// keyboard row, so the estimate takes time linear in the password's length;
+// longer repeats and sequences are scored as several shorter ones.
+func EstimatePasswordEntropy(password string) float64 {
+	runes := []rune(password)
+	n := len(runes)
+	if n == 0 {
+		return 0
+	}
+
+	bruteForce := math.Log2(float64(charsetSize(runes)))
+	breachedPasswordRanks() // sets maxPatternLength
+
+	// best[j] is the minimum entropy of runes[:j].
+	best := make([]float64, n+1)
+	for j := 1; j <= n; j++ {
+		best[j] = best[j-1] + bruteForce
+		for i := max(0, j-maxPatternLength); i <= j-3; i++ {
+			if bits, ok := patternEntropy(runes[i:j]); ok && best[i]+bits < best[j] {
+				best[j] = best[i] + bits
+			}
+		}
+	}
+
+	return best[n]
+}
+
+// patternEntropy returns the entropy of s if it matches a guessable pattern.
+func patternEntropy(s []rune) (float64, bool) {
+	bits := math.Inf(1)
+
+	if b, ok := dictionaryEntropy(s); ok {
+		bits = math.Min(bits, b)
+	}
+	if b, ok := repeatEntropy(s); ok {
+		bits = math.Min(bits, b)
+	}
+	if b, ok := sequenceEntropy(s); ok {
+		bits = math.Min(bits, b)
+	}
+	if b, ok := keyboardEntropy(s); ok {
+		bits = math.Min(bits, b)
+	}
+
+	return bits, !math.IsInf(bits, 1)
+}
+
+func dictionaryEntropy(s []rune) (float64, bool) {
+	ranks := breachedPasswordRanks()
+	lower := strings.ToLower(string(s))
+
+	if rank, ok := ranks[lower]; ok {
+		return math.Log2(float64(rank)) + capitalizationEntropy(s), true
+	}
+
+	substituted := 0
+	unleeted := []rune(lower)
+	for i, r := range unleeted {
+		if plain, ok := leetSubstitutions[r]; ok {
+			unleeted[i] = plain
+			substituted++
+		}
+	}
+	if substituted == 0 {
+		return 0, false
+	}
+	if rank, ok := ranks[string(unleeted)]; ok {
+		return math.Log2(float64(rank)) + capitalizationEntropy(s) + float64(substituted), true
+	}
+	return 0, false
+}
+
+// capitalizationEntropy approximates the extra guesses needed for the case
+// pattern of a dictionary word: none for all lower, one bit for the common
+// first-letter or all-caps forms, and one bit per uppercase letter otherwise.
+func capitalizationEntropy(s []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0, upper == 1 && unicode.IsUpper(s[0]):
+		return 1
+	default:
+		return float64(upper)
+	}
+}
+
+func repeatEntropy(s []rune) (float64, bool) {
+	for _, r := range s[1:] {
+		if r != s[0] {
+			return 0, false
+		}
+	}
+	return math.Log2(float64(charsetSize(s[:1]) * len(s))), true
+}
+
+func sequenceEntropy(s []rune) (float64, bool) {
+	delta := s[1] - s[0]
+	if delta != 1 && delta != -1 {
+		return 0, false
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i]-s[i-1] != delta {
+			return 0, false
+		}
+	}
+
+	var base float64
+	switch first := unicode.ToLower(s[0]); {
+	case first == 'a' || first == 'z' || first == '0' || first == '1' || first == '9':
+		base = 2
+	case unicode.IsDigit(first):
+		base = math.Log2(10)
+	default:
+		base = math.Log2(26)
+	}
+	if delta < 0 {
+		base++
+	}
+	return base + math.Log2(float64(len(s))), true
+}
+
+func keyboardEntropy(s []rune) (float64, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	lower := strings.ToLower(string(s))
+	reversed := []rune(lower)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+
+	for _, row := range keyboardRows {
+		if strings.Contains(row, lower) || strings.Contains(row, string(reversed)) {
+			// Roughly: pick one of ~47 starting keys, a direction and a length.
+			return math.Log2(47*2*float64(len(s))) + capitalizationEntropy(s), true
+		}
+	}
+	return 0, false
+}
+
+// charsetSize estimates the alphabet an attacker must brute force for s.
+func charsetSize(s []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return size
+}
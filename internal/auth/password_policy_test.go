@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := DefaultPasswordPolicy
+
+	cases := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{
+			name:     "strong passphrase",
+			password: "violet-anchor-Pebble-92",
+			email:    "walt@example.com",
+		},
+		{
+			name:     "too short and breached",
+			password: "dragon",
+			email:    "walt@example.com",
+			want:     []string{PasswordRuleMinLength, PasswordRuleBreached, PasswordRuleMinEntropy},
+		},
+		{
+			name:     "breached with l33t capitalisation",
+			password: "P@ssw0rd",
+			email:    "walt@example.com",
+			want:     []string{PasswordRuleMinEntropy},
+		},
+		{
+			name:     "contains email local part",
+			password: "Walter-rides-bikes-77",
+			email:    "walter@example.com",
+			want:     []string{PasswordRuleContainsEmail},
+		},
+		{
+			name:     "keyboard run",
+			password: "ytrewqasdfgh",
+			email:    "walt@example.com",
+			want:     []string{PasswordRuleMinEntropy},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, tc.email)
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Check() error = %v, want *PasswordPolicyError", err)
+			}
+
+			got := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				got = append(got, v.Rule)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Check() rules = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("Check() rules = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsBreachedPassword(t *testing.T) {
+	if !IsBreachedPassword("Password1") {
+		t.Fatalf("IsBreachedPassword(Password1) = false, want true")
+	}
+	if IsBreachedPassword("violet-anchor-Pebble-92") {
+		t.Fatalf("IsBreachedPassword() = true for uncommon passphrase")
+	}
+}
+
+func TestEstimatePasswordEntropyOrdering(t *testing.T) {
+	weak := EstimatePasswordEntropy("password123")
+	strong := EstimatePasswordEntropy("kx9$Lm2#qP")
+	if weak >= strong {
+		t.Fatalf("EstimatePasswordEntropy() weak = %.1f, strong = %.1f; want weak < strong", weak, strong)
+	}
+	if got := EstimatePasswordEntropy(""); got != 0 {
+		t.Fatalf("EstimatePasswordEntropy(\"\") = %.1f, want 0", got)
+	}
+}
+
+func TestPasswordPolicyCheckLength(t *testing.T) {
+	// Varied characters so that no long pattern shortcuts the estimate.
+	long := []rune{}
+	for i := 0; len(long) < MaxPasswordLength; i++ {
+		long = append(long, rune('!'+i*7%94))
+	}
+
+	start := time.Now()
+	err := DefaultPasswordPolicy.Check(string(long), "walt@example.com")
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Check() of a %d-character password took %v", MaxPasswordLength, elapsed)
+	}
+	if err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+
+	var policyErr *PasswordPolicyError
+	err = DefaultPasswordPolicy.Check(string(long)+"x", "walt@example.com")
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 || policyErr.Violations[0].Rule != PasswordRuleMaxLength {
+		t.Errorf("Check() of an over-long password = %v, want only %s", err, PasswordRuleMaxLength)
+	}
+}
+
+func TestEstimatePasswordEntropyIsLinear(t *testing.T) {
+	start := time.Now()
+	EstimatePasswordEntropy(strings.Repeat("correct horse battery staple ", 400))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("EstimatePasswordEntropy() of 11,600 characters took %v", elapsed)
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Role is the access level of a Chirpy account.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// AtLeast reports whether r grants at least the privileges of min.
+// Unknown roles grant nothing.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[min]
+}
+
+// RequireRole rejects requests whose principal does not hold at least the
+// given role with 403 Forbidden. It must be wrapped by Required.
+func (a *Authenticator) RequireRole(min Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				a.unauthorized(w, r, "")
+				return
+			}
+			if !p.Role.AtLeast(min) {
+				a.forbidden(w, r, "insufficient_role", "")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
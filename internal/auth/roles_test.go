@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRoleAtLeast(t *testing.T) {
+	cases := []struct {
+		role Role
+		min  Role
+		want bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleUser, RoleModerator, false},
+		{Role("superuser"), RoleUser, false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.role.AtLeast(tc.min); got != tc.want {
+			t.Errorf("%q.AtLeast(%q) = %v, want %v", tc.role, tc.min, got, tc.want)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	if role, err := ParseRole("moderator"); err != nil || role != RoleModerator {
+		t.Fatalf("ParseRole(moderator) = %q, %v", role, err)
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Fatalf("ParseRole(root) expected error")
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	authn := NewAuthenticator("test-secret", nil)
+	handler := authn.RequireRole(RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		role   Role
+		status int
+	}{
+		{name: "admin", role: RoleAdmin, status: http.StatusNoContent},
+		{name: "moderator", role: RoleModerator, status: http.StatusForbidden},
+		{name: "user", role: RoleUser, status: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/metrics", nil)
+			req = req.WithContext(WithPrincipal(req.Context(), &Principal{UserID: uuid.New(), Role: tc.role}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
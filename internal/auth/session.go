@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Cookie and header names used by browser sessions.
+const (
+	AccessTokenCookie  = "chirpy_access"
+	RefreshTokenCookie = "chirpy_refresh"
+	CSRFCookie         = "chirpy_csrf"
+	CSRFHeader         = "X-CSRF-Token"
+	CSRFFormField      = "csrf_token"
+)
+
+// ErrInvalidCSRFToken is returned when a cookie-authenticated request does not
+// echo the CSRF cookie in the CSRF header.
+var ErrInvalidCSRFToken = errors.New("csrf token missing or invalid")
+
+// SessionCookieOptions controls attributes shared by all session cookies.
+type SessionCookieOptions struct {
+	// Secure restricts the cookies to HTTPS. It should only be false in local development.
+	Secure bool
+}
+
+// SessionTokens are the credentials written to cookies by SetSessionCookies.
+type SessionTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	CSRFToken        string
+}
+
+// MakeCSRFToken generates a random token for double-submit CSRF protection.
+func MakeCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// SetSessionCookies stores a browser session. The access and refresh tokens
+// are HttpOnly so scripts cannot read them; the CSRF token is readable so the
+// app can echo it in the X-CSRF-Token header. Empty tokens are skipped, which
+// lets the refresh endpoint replace only the access token.
+func SetSessionCookies(w http.ResponseWriter, opts SessionCookieOptions, tokens SessionTokens) {
+	if tokens.AccessToken != "" {
+		http.SetCookie(w, sessionCookie(opts, AccessTokenCookie, tokens.AccessToken, "/", tokens.AccessExpiresAt, true))
+	}
+	if tokens.RefreshToken != "" {
+		http.SetCookie(w, sessionCookie(opts, RefreshTokenCookie, tokens.RefreshToken, "/api", tokens.RefreshExpiresAt, true))
+	}
+	if tokens.CSRFToken != "" {
+		http.SetCookie(w, sessionCookie(opts, CSRFCookie, tokens.CSRFToken, "/", tokens.RefreshExpiresAt, false))
+	}
+}
+
+// ClearSessionCookies expires every session cookie.
+func ClearSessionCookies(w http.ResponseWriter, opts SessionCookieOptions) {
+	for _, c := range []struct {
+		name, path string
+		httpOnly   bool
+	}{
+		{AccessTokenCookie, "/", true},
+		{RefreshTokenCookie, "/api", true},
+		{CSRFCookie, "/", false},
+	} {
+		cookie := sessionCookie(opts, c.name, "", c.path, time.Unix(0, 0), c.httpOnly)
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+func sessionCookie(opts SessionCookieOptions, name, value, path string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: httpOnly,
+		Secure:   opts.Secure,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// GetRefreshToken returns the refresh token from the Authorization header or,
+// failing that, the refresh cookie. fromCookie reports which was used so that
+// callers can enforce CheckCSRF on cookie-based requests.
+func GetRefreshToken(r *http.Request) (token string, fromCookie bool, err error) {
+	if r.Header.Get("Authorization") != "" {
+		token, err := GetBearerToken(r.Header)
+		return token, false, err
+	}
+
+	cookie, err := r.Cookie(RefreshTokenCookie)
+	if err != nil || cookie.Value == "" {
+		return "", false, errors.New("refresh token missing")
+	}
+	return cookie.Value, true, nil
+}
+
+// CheckCSRF verifies the double-submit CSRF token for state-changing requests.
+// The token may be sent in the X-CSRF-Token header or, for HTML forms, a
+// csrf_token form field. Safe methods (GET, HEAD, OPTIONS, TRACE) always pass.
+func CheckCSRF(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return nil
+	}
+
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return ErrInvalidCSRFToken
+	}
+
+	header := r.Header.Get(CSRFHeader)
+	if header == "" && isFormPost(r) {
+		header = r.PostFormValue(CSRFFormField)
+	}
+	if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+	return nil
+}
+
+func isFormPost(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded"
+}
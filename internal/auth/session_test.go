@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSetSessionCookies(t *testing.T) {
+	rec := httptest.NewRecorder()
+	expires := time.Now().Add(time.Hour)
+	SetSessionCookies(rec, SessionCookieOptions{Secure: true}, SessionTokens{
+		AccessToken:      "access",
+		AccessExpiresAt:  expires,
+		RefreshToken:     "refresh",
+		RefreshExpiresAt: expires,
+		CSRFToken:        "csrf",
+	})
+
+	cookies := make(map[string]*http.Cookie)
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+
+	for _, name := range []string{AccessTokenCookie, RefreshTokenCookie, CSRFCookie} {
+		c, ok := cookies[name]
+		if !ok {
+			t.Fatalf("missing cookie %s", name)
+		}
+		if !c.Secure || c.SameSite != http.SameSiteStrictMode {
+			t.Errorf("cookie %s Secure = %v SameSite = %v, want Secure Strict", name, c.Secure, c.SameSite)
+		}
+	}
+
+	if !cookies[AccessTokenCookie].HttpOnly || !cookies[RefreshTokenCookie].HttpOnly {
+		t.Errorf("token cookies must be HttpOnly")
+	}
+	if cookies[CSRFCookie].HttpOnly {
+		t.Errorf("CSRF cookie must be readable by scripts")
+	}
+	if cookies[RefreshTokenCookie].Path != "/api" {
+		t.Errorf("refresh cookie path = %q, want /api", cookies[RefreshTokenCookie].Path)
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		cookie string
+		header string
+		form   string
+		ok     bool
+	}{
+		{name: "safe method", method: http.MethodGet, ok: true},
+		{name: "matching form field", method: http.MethodPost, cookie: "abc", form: "abc", ok: true},
+		{name: "mismatched form field", method: http.MethodPost, cookie: "abc", form: "abd"},
+		{name: "matching tokens", method: http.MethodPost, cookie: "abc", header: "abc", ok: true},
+		{name: "missing header", method: http.MethodPost, cookie: "abc"},
+		{name: "missing cookie", method: http.MethodDelete, header: "abc"},
+		{name: "mismatch", method: http.MethodPut, cookie: "abc", header: "abd"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.form != "" {
+				req = httptest.NewRequest(tc.method, "/", strings.NewReader(CSRFFormField+"="+tc.form))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: tc.cookie})
+			}
+			if tc.header != "" {
+				req.Header.Set(CSRFHeader, tc.header)
+			}
+
+			err := CheckCSRF(req)
+			if tc.ok && err != nil {
+				t.Fatalf("CheckCSRF() error = %v, want nil", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidCSRFToken) {
+				t.Fatalf("CheckCSRF() error = %v, want ErrInvalidCSRFToken", err)
+			}
+		})
+	}
+}
+
+func TestGetRefreshToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: RefreshTokenCookie, Value: "from-cookie"})
+
+	token, fromCookie, err := GetRefreshToken(req)
+	if err != nil || token != "from-cookie" || !fromCookie {
+		t.Fatalf("GetRefreshToken() = %q, %v, %v; want cookie token", token, fromCookie, err)
+	}
+
+	req.Header.Set("Authorization", "Bearer from-header")
+	token, fromCookie, err = GetRefreshToken(req)
+	if err != nil || token != "from-header" || fromCookie {
+		t.Fatalf("GetRefreshToken() = %q, %v, %v; want header token", token, fromCookie, err)
+	}
+}
+
+func TestAuthenticatorCookieSession(t *testing.T) {
+	secret := "test-secret"
+	token, err := MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	authn := NewAuthenticator(secret, nil)
+	handler := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		method string
+		csrf   string
+		status int
+	}{
+		{name: "read without csrf", method: http.MethodGet, status: http.StatusNoContent},
+		{name: "write with csrf", method: http.MethodPost, csrf: "csrf-value", status: http.StatusNoContent},
+		{name: "write without csrf", method: http.MethodPost, status: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/chirps", nil)
+			req.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: token})
+			req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf-value"})
+			if tc.csrf != "" {
+				req.Header.Set(CSRFHeader, tc.csrf)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+			}
+		})
+	}
+}
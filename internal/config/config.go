@@ -0,0 +1,564 @@
+// Package config loads Chirpy's settings from built-in defaults, an optional
+// YAML or TOML file, environment variables and command-line flags, in
+// increasing order of precedence.
+//
+// Every setting has a dotted file key (server.addr), an environment variable
+// (HTTP_ADDR) and, unless it is a secret, a flag named after the variable
+// (--http-addr). Secrets are never accepted as flags; instead they can be read
+// from a file named by <VAR>_FILE or the matching "_file" key, which suits
+// Docker and Kubernetes secret mounts.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/oidc"
+	"chirpy/internal/storage"
+	"chirpy/internal/tracing"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Platforms Chirpy can run as. Only dev enables /admin/reset, plain-HTTP
+// session cookies and webhooks to private addresses.
+const (
+	PlatformDev     = "dev"
+	PlatformStaging = "staging"
+	PlatformProd    = "prod"
+)
+
+// FileEnv names the config file when --config is not given.
+const FileEnv = "CHIRPY_CONFIG"
+
+// Config is the complete server configuration.
+type Config struct {
+	// Platform has no default, so a deployment that forgets to set it fails
+	// to start instead of running with development behaviour.
+	Platform string         `yaml:"platform" toml:"platform"`
+	Server   Server         `yaml:"server" toml:"server"`
+	Database Database       `yaml:"database" toml:"database"`
+	Auth     Auth           `yaml:"auth" toml:"auth"`
+	Polka    Polka          `yaml:"polka" toml:"polka"`
+	OIDC     []OIDCProvider `yaml:"oidc_providers" toml:"oidc_providers"`
+	Log      Log            `yaml:"log" toml:"log"`
+	Tracing  Tracing        `yaml:"tracing" toml:"tracing"`
+}
+
+// Server holds the HTTP listener settings.
+type Server struct {
+	Addr              string   `yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes    int      `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once a shutdown signal arrives.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// ShutdownDelay keeps serving, while /api/readyz reports not ready, for
+	// this long after a shutdown signal, so load balancers stop sending new
+	// requests before the listener closes.
+	ShutdownDelay Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+}
+
+// Database holds the connection settings.
+type Database struct {
+	// URL is a postgres:// URL, or a sqlite: URL such as sqlite:chirpy.db
+	// for single-node and development deployments.
+	URL     Secret `yaml:"url" toml:"url"`
+	URLFile string `yaml:"url_file,omitempty" toml:"url_file,omitempty"`
+	// AutoMigrate applies pending migrations when the server starts instead
+	// of requiring "chirpy migrate up".
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+	// MaxConns caps the PostgreSQL connection pool, and MaxConnIdleTime
+	// closes connections left idle for longer.
+	MaxConns        int      `yaml:"max_conns" toml:"max_conns"`
+	MaxConnIdleTime Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time"`
+	// StatementCacheCapacity is how many prepared statements each connection
+	// keeps; 0 stops preparing statements, for PgBouncer in transaction mode.
+	StatementCacheCapacity int `yaml:"statement_cache_capacity" toml:"statement_cache_capacity"`
+}
+
+// Pool converts d's pool settings to the storage package's.
+func (d Database) Pool() storage.PoolConfig {
+	return storage.PoolConfig{
+		MaxConns:               int32(d.MaxConns),
+		MaxConnIdleTime:        d.MaxConnIdleTime.Std(),
+		StatementCacheCapacity: d.StatementCacheCapacity,
+	}
+}
+
+// Auth holds token signing, password hashing and login throttling settings.
+type Auth struct {
+	JWTSecret     Secret         `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWTSecretFile string         `yaml:"jwt_secret_file,omitempty" toml:"jwt_secret_file,omitempty"`
+	Argon2        Argon2         `yaml:"argon2" toml:"argon2"`
+	Password      PasswordPolicy `yaml:"password" toml:"password"`
+	Lockout       Lockout        `yaml:"lockout" toml:"lockout"`
+}
+
+// Argon2 holds the Argon2id cost settings for new password hashes.
+type Argon2 struct {
+	MemoryKiB   uint32 `yaml:"memory_kib" toml:"memory_kib"`
+	Iterations  uint32 `yaml:"iterations" toml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism" toml:"parallelism"`
+}
+
+// PasswordParams converts a to the auth package's parameters.
+func (a Argon2) PasswordParams() auth.PasswordParams {
+	return auth.PasswordParams{Memory: a.MemoryKiB, Iterations: a.Iterations, Parallelism: a.Parallelism}
+}
+
+// PasswordPolicy holds the tunable parts of the password policy.
+type PasswordPolicy struct {
+	MinLength      int     `yaml:"min_length" toml:"min_length"`
+	MinEntropyBits float64 `yaml:"min_entropy_bits" toml:"min_entropy_bits"`
+}
+
+// Policy returns auth.DefaultPasswordPolicy with p's limits applied.
+func (p PasswordPolicy) Policy() auth.PasswordPolicy {
+	policy := auth.DefaultPasswordPolicy
+	policy.MinLength = p.MinLength
+	policy.MinEntropyBits = p.MinEntropyBits
+	return policy
+}
+
+// Lockout holds the login throttles, one per account and one per client IP.
+type Lockout struct {
+	Account LockoutPolicy `yaml:"account" toml:"account"`
+	IP      LockoutPolicy `yaml:"ip" toml:"ip"`
+}
+
+// LockoutPolicy holds one login throttle; see auth.LockoutPolicy. A zero
+// Threshold disables it.
+type LockoutPolicy struct {
+	Threshold int      `yaml:"threshold" toml:"threshold"`
+	BaseDelay Duration `yaml:"base_delay" toml:"base_delay"`
+	MaxDelay  Duration `yaml:"max_delay" toml:"max_delay"`
+	Window    Duration `yaml:"window" toml:"window"`
+}
+
+func lockoutPolicy(p auth.LockoutPolicy) LockoutPolicy {
+	return LockoutPolicy{
+		Threshold: p.Threshold,
+		BaseDelay: Duration(p.BaseDelay),
+		MaxDelay:  Duration(p.MaxDelay),
+		Window:    Duration(p.Window),
+	}
+}
+
+// Policy converts p to the auth package's policy.
+func (p LockoutPolicy) Policy() auth.LockoutPolicy {
+	return auth.LockoutPolicy{
+		Threshold: p.Threshold,
+		BaseDelay: p.BaseDelay.Std(),
+		MaxDelay:  p.MaxDelay.Std(),
+		Window:    p.Window.Std(),
+	}
+}
+
+// Polka holds the credentials for the Polka payments webhook. The signing
+// secret is preferred; the static key is kept for older integrations.
+type Polka struct {
+	Key               Secret `yaml:"key" toml:"key"`
+	KeyFile           string `yaml:"key_file,omitempty" toml:"key_file,omitempty"`
+	WebhookSecret     Secret `yaml:"webhook_secret" toml:"webhook_secret"`
+	WebhookSecretFile string `yaml:"webhook_secret_file,omitempty" toml:"webhook_secret_file,omitempty"`
+}
+
+// OIDCProvider configures an external OpenID Connect login provider.
+type OIDCProvider struct {
+	Name             string   `yaml:"name" toml:"name"`
+	Issuer           string   `yaml:"issuer" toml:"issuer"`
+	ClientID         string   `yaml:"client_id" toml:"client_id"`
+	ClientSecret     Secret   `yaml:"client_secret" toml:"client_secret"`
+	ClientSecretFile string   `yaml:"client_secret_file,omitempty" toml:"client_secret_file,omitempty"`
+	RedirectURL      string   `yaml:"redirect_url" toml:"redirect_url"`
+	Scopes           []string `yaml:"scopes,omitempty" toml:"scopes,omitempty"`
+}
+
+// OIDCConfig converts p for oidc.Discover.
+func (p OIDCProvider) OIDCConfig() oidc.Config {
+	return oidc.Config{
+		Name:         p.Name,
+		IssuerURL:    p.Issuer,
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret.Value(),
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+	}
+}
+
+// Log holds logging settings.
+type Log struct {
+	Level slog.Level `yaml:"level" toml:"level"`
+}
+
+// Tracing holds OpenTelemetry settings. Endpoints, headers and TLS for the
+// OTLP exporter still come from the standard OTEL_EXPORTER_OTLP_* variables.
+type Tracing struct {
+	// Exporter is one of the tracing.Exporter constants. When unset, spans
+	// are exported over OTLP only if an OTLP endpoint is configured.
+	Exporter string `yaml:"exporter" toml:"exporter"`
+}
+
+// Default returns the built-in defaults. Platform and the secrets are left
+// empty on purpose.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(15 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(120 * time.Second),
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Database: Database{
+			MaxConns:               int(storage.DefaultPoolConfig.MaxConns),
+			MaxConnIdleTime:        Duration(storage.DefaultPoolConfig.MaxConnIdleTime),
+			StatementCacheCapacity: storage.DefaultPoolConfig.StatementCacheCapacity,
+		},
+		Auth: Auth{
+			Argon2: Argon2{
+				MemoryKiB:   auth.DefaultPasswordParams.Memory,
+				Iterations:  auth.DefaultPasswordParams.Iterations,
+				Parallelism: auth.DefaultPasswordParams.Parallelism,
+			},
+			Password: PasswordPolicy{
+				MinLength:      auth.DefaultPasswordPolicy.MinLength,
+				MinEntropyBits: auth.DefaultPasswordPolicy.MinEntropyBits,
+			},
+			Lockout: Lockout{
+				Account: lockoutPolicy(auth.DefaultAccountLockout),
+				IP:      lockoutPolicy(auth.DefaultIPLockout),
+			},
+		},
+		Log: Log{Level: slog.LevelInfo},
+	}
+}
+
+// Invocation is the result of parsing Chirpy's command line.
+type Invocation struct {
+	Config *Config
+	// PrintConfig is set by --print-config.
+	PrintConfig bool
+	// Args are the arguments left after the flags, e.g. a subcommand.
+	Args []string
+}
+
+// Load parses args (without the program name) and builds the configuration.
+// lookupEnv is normally os.LookupEnv; variables set to "" count as unset.
+// A --help flag returns flag.ErrHelp after printing usage to stderr.
+//
+// Load does not call Validate, since subcommands need only part of the
+// configuration.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Invocation, error) {
+	getenv := func(name string) string {
+		v, _ := lookupEnv(name)
+		return v
+	}
+
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	configFile := fs.String("config", getenv(FileEnv), "YAML or TOML config file (env "+FileEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		if s.secret() {
+			continue
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.key, s.env)
+		collect := func(v string) error {
+			flagValues = append(flagValues, flagValue{s, v})
+			return nil
+		}
+		if _, ok := s.field(&Config{}).(*bool); ok {
+			fs.BoolFunc(s.flagName(), usage, collect)
+		} else {
+			fs.Func(s.flagName(), usage, collect)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		errs = append(errs, s.applyEnv(&cfg, getenv))
+	}
+	errs = append(errs, applyOIDCEnv(&cfg, getenv))
+	for _, f := range flagValues {
+		if err := setValue(f.setting.field(&cfg), f.value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --%s %q: %w", f.setting.flagName(), f.value, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.readSecretFiles(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = tracing.ExporterNone
+		if getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+			cfg.Tracing.Exporter = tracing.ExporterOTLP
+		}
+	}
+
+	return &Invocation{Config: &cfg, PrintConfig: *printConfig, Args: fs.Args()}, nil
+}
+
+// loadFile decodes a YAML (.yaml, .yml) or TOML (.toml) file over cfg.
+// Unknown keys are rejected so typos do not go unnoticed.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q (want .yaml, .yml or .toml)", path, ext)
+	}
+
+	for _, s := range cfg.secrets() {
+		if *s.value != "" && *s.file != "" {
+			return fmt.Errorf("config file %s: set only one of %s and %s_file", path, s.key, s.key)
+		}
+	}
+	return nil
+}
+
+// Validate reports every problem that would stop the server from running
+// safely.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Platform {
+	case PlatformDev, PlatformStaging, PlatformProd:
+	case "":
+		add("platform is not set; set PLATFORM to %s, %s or %s", PlatformDev, PlatformStaging, PlatformProd)
+	default:
+		add("invalid platform %q; want %s, %s or %s", c.Platform, PlatformDev, PlatformStaging, PlatformProd)
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr must not be empty")
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		add("server.max_header_bytes must be positive")
+	}
+
+	if c.Database.URL == "" {
+		add("database.url is not set (DB_URL)")
+	}
+	if c.Database.MaxConns <= 0 || c.Database.MaxConns > math.MaxInt32 {
+		add("database.max_conns must be positive")
+	}
+	if c.Database.MaxConnIdleTime < 0 || c.Database.StatementCacheCapacity < 0 {
+		add("database.max_conn_idle_time and statement_cache_capacity must not be negative")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		add("auth.jwt_secret is not set (JWT_SECRET)")
+	} else if c.Platform != PlatformDev && len(c.Auth.JWTSecret) < minJWTSecretLength {
+		add("auth.jwt_secret must be at least %d bytes outside dev", minJWTSecretLength)
+	}
+	if err := c.Auth.Argon2.PasswordParams().Validate(); err != nil {
+		add("auth.argon2: %w", err)
+	}
+	if c.Auth.Password.MinLength < 0 || c.Auth.Password.MinEntropyBits < 0 {
+		add("auth.password limits must not be negative")
+	}
+	if c.Auth.Password.MinLength > auth.MaxPasswordLength {
+		add("auth.password.min_length must be at most %d", auth.MaxPasswordLength)
+	}
+	for _, l := range []struct {
+		name   string
+		policy LockoutPolicy
+	}{{"account", c.Auth.Lockout.Account}, {"ip", c.Auth.Lockout.IP}} {
+		p := l.policy
+		switch {
+		case p.Threshold < 0:
+			add("auth.lockout.%s.threshold must not be negative", l.name)
+		case p.Threshold > 0 && (p.BaseDelay <= 0 || p.MaxDelay < p.BaseDelay || p.Window <= 0):
+			add("auth.lockout.%s needs a positive base_delay and window, and a max_delay of at least base_delay", l.name)
+		}
+	}
+
+	if c.Polka.Key == "" && c.Polka.WebhookSecret == "" {
+		add("polka.webhook_secret (POLKA_WEBHOOK_SECRET) or polka.key (POLKA_KEY) must be set")
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range c.OIDC {
+		switch {
+		case p.Name == "" || p.Name != strings.ToLower(p.Name):
+			add("oidc provider name %q must be non-empty and lower case", p.Name)
+		case seen[p.Name]:
+			add("oidc provider %q is configured twice", p.Name)
+		case p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "":
+			add("oidc provider %q needs an issuer, client_id and redirect_url", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterOTLP, tracing.ExporterConsole, "stdout", tracing.ExporterNone:
+	default:
+		add("invalid tracing.exporter %q", c.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
+
+// minJWTSecretLength matches the HS256 key size.
+const minJWTSecretLength = 32
+
+// Print writes the configuration to w as YAML with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type secretRef struct {
+	key   string
+	value *Secret
+	file  *string
+}
+
+func (c *Config) secrets() []secretRef {
+	refs := []secretRef{
+		{"database.url", &c.Database.URL, &c.Database.URLFile},
+		{"auth.jwt_secret", &c.Auth.JWTSecret, &c.Auth.JWTSecretFile},
+		{"polka.key", &c.Polka.Key, &c.Polka.KeyFile},
+		{"polka.webhook_secret", &c.Polka.WebhookSecret, &c.Polka.WebhookSecretFile},
+	}
+	for i := range c.OIDC {
+		p := &c.OIDC[i]
+		refs = append(refs, secretRef{"oidc_providers." + p.Name + ".client_secret", &p.ClientSecret, &p.ClientSecretFile})
+	}
+	return refs
+}
+
+// readSecretFiles loads every secret configured by file. Trailing newlines
+// are dropped since most tools that write secret files add one.
+func (c *Config) readSecretFiles() error {
+	for _, s := range c.secrets() {
+		if *s.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(*s.file)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", s.key, err)
+		}
+		*s.value = Secret(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// applyOIDCEnv applies OIDC_PROVIDERS and the per-provider
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET(_FILE), _REDIRECT_URL and
+// _SCOPES variables. OIDC_PROVIDERS, a comma-separated list of names, replaces
+// the providers from the config file; the other variables override fields of
+// the named provider.
+func applyOIDCEnv(c *Config, getenv func(string) string) error {
+	if list := getenv("OIDC_PROVIDERS"); list != "" {
+		var providers []OIDCProvider
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(strings.ToLower(name))
+			if name == "" {
+				continue
+			}
+			i := slices.IndexFunc(c.OIDC, func(p OIDCProvider) bool { return p.Name == name })
+			if i >= 0 {
+				providers = append(providers, c.OIDC[i])
+			} else {
+				providers = append(providers, OIDCProvider{Name: name})
+			}
+		}
+		c.OIDC = providers
+	}
+
+	for i := range c.OIDC {
+		p := &c.OIDC[i]
+		prefix := "OIDC_" + strings.ToUpper(p.Name) + "_"
+		if v := getenv(prefix + "ISSUER"); v != "" {
+			p.Issuer = v
+		}
+		if v := getenv(prefix + "CLIENT_ID"); v != "" {
+			p.ClientID = v
+		}
+		if err := applySecretEnv(prefix+"CLIENT_SECRET", &p.ClientSecret, &p.ClientSecretFile, getenv); err != nil {
+			return err
+		}
+		if v := getenv(prefix + "REDIRECT_URL"); v != "" {
+			p.RedirectURL = v
+		}
+		if v := getenv(prefix + "SCOPES"); v != "" {
+			p.Scopes = strings.Fields(v)
+		}
+	}
+	return nil
+}
+
+// applySecretEnv sets a secret from env or the file named by env_FILE,
+// replacing whatever the config file said.
+func applySecretEnv(env string, value *Secret, file *string, getenv func(string) string) error {
+	v, f := getenv(env), getenv(env+"_FILE")
+	switch {
+	case v != "" && f != "":
+		return fmt.Errorf("set only one of %s and %s_FILE", env, env)
+	case v != "":
+		*value, *file = Secret(v), ""
+	case f != "":
+		*value, *file = "", f
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/tracing"
+)
+
+func envFrom(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "chirpy.yaml", `
+platform: staging
+server:
+  addr: ":7000"
+  write_timeout: 45s
+  read_timeout: 20s
+log:
+  level: debug
+`)
+
+	inv, err := Load([]string{"--http-read-timeout", "1m", "--db-auto-migrate", "bootstrap-admin", "-email", "a@example.com"}, envFrom(map[string]string{
+		FileEnv:                      file,
+		"HTTP_ADDR":                  "127.0.0.1:9000",
+		"HTTP_IDLE_TIMEOUT":          "",
+		"PLATFORM":                   "prod",
+		"DB_MAX_CONNS":               "25",
+		"LOGIN_IP_LOCKOUT_THRESHOLD": "50",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg := inv.Config
+
+	if cfg.Platform != PlatformProd || cfg.Server.Addr != "127.0.0.1:9000" {
+		t.Errorf("environment did not override the file: %+v", cfg)
+	}
+	if cfg.Server.WriteTimeout.Std() != 45*time.Second || cfg.Log.Level != slog.LevelDebug {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Server.ReadTimeout.Std() != time.Minute {
+		t.Errorf("flag did not override the file: read timeout = %v", cfg.Server.ReadTimeout)
+	}
+	if !cfg.Database.AutoMigrate {
+		t.Error("boolean flag without a value was not applied")
+	}
+	if pool := cfg.Database.Pool(); pool.MaxConns != 25 || pool.StatementCacheCapacity != 512 {
+		t.Errorf("Database.Pool() = %+v, want 25 connections and the default statement cache", pool)
+	}
+	if cfg.Server.IdleTimeout != Default().Server.IdleTimeout || cfg.Server.MaxHeaderBytes != 1<<20 {
+		t.Errorf("unset values did not fall back to defaults: %+v", cfg.Server)
+	}
+	if ip := cfg.Auth.Lockout.IP.Policy(); ip.Threshold != 50 || ip.Window != auth.DefaultIPLockout.Window {
+		t.Errorf("IP lockout = %+v, want threshold 50 and the default window", ip)
+	}
+	if cfg.Tracing.Exporter != tracing.ExporterNone {
+		t.Errorf("tracing exporter = %q, want none without an OTLP endpoint", cfg.Tracing.Exporter)
+	}
+	if got := strings.Join(inv.Args, " "); got != "bootstrap-admin -email a@example.com" {
+		t.Errorf("Args = %q", got)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, "chirpy.toml", `
+platform = "dev"
+
+[server]
+shutdown_timeout = "10s"
+
+[auth.argon2]
+memory_kib = 19456
+
+[[oidc_providers]]
+name = "google"
+issuer = "https://accounts.google.com"
+client_id = "chirpy"
+redirect_url = "https://chirpy.example/api/auth/oidc/google/callback"
+`)
+
+	inv, err := Load([]string{"--config", file}, envFrom(map[string]string{
+		"OIDC_GOOGLE_CLIENT_SECRET": "shh",
+		"OIDC_GOOGLE_SCOPES":        "email",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg := inv.Config
+
+	if cfg.Server.ShutdownTimeout.Std() != 10*time.Second || cfg.Auth.Argon2.MemoryKiB != 19456 {
+		t.Errorf("TOML values not applied: %+v", cfg)
+	}
+	if len(cfg.OIDC) != 1 || cfg.OIDC[0].ClientSecret.Value() != "shh" || cfg.OIDC[0].Scopes[0] != "email" {
+		t.Errorf("OIDC providers = %+v", cfg.OIDC)
+	}
+}
+
+func TestLoadOIDCProvidersFromEnv(t *testing.T) {
+	inv, err := Load(nil, envFrom(map[string]string{
+		"OIDC_PROVIDERS":           "GitHub, ",
+		"OIDC_GITHUB_ISSUER":       "https://github.example",
+		"OIDC_GITHUB_CLIENT_ID":    "id",
+		"OIDC_GITHUB_REDIRECT_URL": "https://chirpy.example/cb",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got := inv.Config.OIDC
+	if len(got) != 1 || got[0].Name != "github" || got[0].OIDCConfig().IssuerURL != "https://github.example" {
+		t.Errorf("OIDC providers = %+v", got)
+	}
+}
+
+func TestLoadSecretsFromFiles(t *testing.T) {
+	dbURLFile := writeFile(t, "db_url", "postgres://chirpy@db/chirpy\n")
+	jwtFile := writeFile(t, "jwt", "from-file")
+	file := writeFile(t, "chirpy.yaml", "auth:\n  jwt_secret_file: "+jwtFile+"\n")
+
+	inv, err := Load([]string{"--config", file}, envFrom(map[string]string{"DB_URL_FILE": dbURLFile}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := inv.Config.Database.URL.Value(); got != "postgres://chirpy@db/chirpy" {
+		t.Errorf("database URL = %q", got)
+	}
+	if got := inv.Config.Auth.JWTSecret.Value(); got != "from-file" {
+		t.Errorf("JWT secret = %q", got)
+	}
+
+	// The environment replaces a file reference from the config file.
+	inv, err = Load([]string{"--config", file}, envFrom(map[string]string{"JWT_SECRET": "from-env"}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := inv.Config.Auth.JWTSecret.Value(); got != "from-env" {
+		t.Errorf("JWT secret = %q, want the environment value", got)
+	}
+
+	if _, err := Load(nil, envFrom(map[string]string{"POLKA_KEY": "k", "POLKA_KEY_FILE": dbURLFile})); err == nil {
+		t.Error("Load() accepted both POLKA_KEY and POLKA_KEY_FILE")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"invalid duration", nil, map[string]string{"HTTP_IDLE_TIMEOUT": "forever"}},
+		{"negative duration", nil, map[string]string{"SHUTDOWN_TIMEOUT": "-1s"}},
+		{"invalid level", []string{"--log-level", "loud"}, nil},
+		{"invalid bool", nil, map[string]string{"DB_AUTO_MIGRATE": "sometimes"}},
+		{"secret as flag", []string{"--jwt-secret", "x"}, nil},
+		{"unknown key", []string{"--config", "unknown.yaml"}, nil},
+		{"both secret and file", []string{"--config", "both.yaml"}, nil},
+		{"unsupported format", []string{"--config", "chirpy.json"}, nil},
+		{"missing secret file", nil, map[string]string{"JWT_SECRET_FILE": "/nonexistent/jwt"}},
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"unknown.yaml": "server:\n  adr: \":80\"\n",
+		"both.yaml":    "database:\n  url: postgres://x\n  url_file: /run/secrets/db\n",
+		"chirpy.json":  "{}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := make([]string, len(tt.args))
+			for i, a := range tt.args {
+				if _, ok := files[a]; ok {
+					a = filepath.Join(dir, a)
+				}
+				args[i] = a
+			}
+			if _, err := Load(args, envFrom(tt.env)); err == nil || errors.Is(err, flag.ErrHelp) {
+				t.Errorf("Load() error = %v, want a configuration error", err)
+			}
+		})
+	}
+}
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.Platform = PlatformProd
+	cfg.Database.URL = "postgres://chirpy@db/chirpy"
+	cfg.Auth.JWTSecret = Secret(strings.Repeat("s", minJWTSecretLength))
+	cfg.Polka.WebhookSecret = "whsec"
+	cfg.Tracing.Exporter = tracing.ExporterNone
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(*Config) {}, ""},
+		{"platform unset", func(c *Config) { c.Platform = "" }, "platform is not set"},
+		{"unknown platform", func(c *Config) { c.Platform = "production" }, "invalid platform"},
+		{"missing database", func(c *Config) { c.Database.URL = "" }, "database.url"},
+		{"empty connection pool", func(c *Config) { c.Database.MaxConns = 0 }, "database.max_conns"},
+		{"statement cache disabled", func(c *Config) { c.Database.StatementCacheCapacity = 0 }, ""},
+		{"short jwt secret in prod", func(c *Config) { c.Auth.JWTSecret = "short" }, "at least 32 bytes"},
+		{"short jwt secret in dev", func(c *Config) { c.Platform, c.Auth.JWTSecret = PlatformDev, "short" }, ""},
+		{"no polka credentials", func(c *Config) { c.Polka.WebhookSecret = "" }, "polka"},
+		{"unreachable password length", func(c *Config) { c.Auth.Password.MinLength = 200 }, "auth.password.min_length"},
+		{"lockout disabled", func(c *Config) { c.Auth.Lockout.IP.Threshold = 0 }, ""},
+		{"negative lockout threshold", func(c *Config) { c.Auth.Lockout.Account.Threshold = -1 }, "auth.lockout.account.threshold"},
+		{"lockout without delay", func(c *Config) { c.Auth.Lockout.IP.BaseDelay = 0 }, "auth.lockout.ip"},
+		{"bad argon2", func(c *Config) { c.Auth.Argon2.Iterations = 0 }, "auth.argon2"},
+		{"incomplete oidc provider", func(c *Config) { c.OIDC = []OIDCProvider{{Name: "google"}} }, "needs an issuer"},
+		{"unknown exporter", func(c *Config) { c.Tracing.Exporter = "jaeger" }, "tracing.exporter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := validConfig()
+	cfg.OIDC = []OIDCProvider{{Name: "google", ClientSecret: "oidc-secret"}}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	out := buf.String()
+	for _, secret := range []string{cfg.Database.URL.Value(), cfg.Auth.JWTSecret.Value(), "whsec", "oidc-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed config leaks %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "write_timeout: 30s") || !strings.Contains(out, "jwt_secret: '[REDACTED]'") {
+		t.Errorf("printed config:\n%s", out)
+	}
+
+	// The printed form loads back, apart from the redacted secrets.
+	var roundTrip Config
+	if err := loadFile(&roundTrip, writeFile(t, "printed.yaml", out)); err != nil {
+		t.Errorf("loading printed config: %v", err)
+	}
+	if roundTrip.Server != cfg.Server || roundTrip.Log != cfg.Log {
+		t.Errorf("round trip = %+v, want %+v", roundTrip, cfg)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	stderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	_, err := Load([]string{"--help"}, envFrom(nil))
+	w.Close()
+	os.Stderr = stderr
+	usage, _ := io.ReadAll(r)
+
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("Load(--help) error = %v, want flag.ErrHelp", err)
+	}
+	if !strings.Contains(string(usage), "-http-addr") || strings.Contains(string(usage), "-jwt-secret") {
+		t.Errorf("usage:\n%s", usage)
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds a scalar Config field to its environment variable and flag.
+type setting struct {
+	// key is the field's dotted path in the config file.
+	key string
+	env string
+	// field returns a pointer to the field in c.
+	field func(c *Config) any
+	// file returns the "_file" companion of a Secret field.
+	file func(c *Config) *string
+}
+
+var settings = []setting{
+	{key: "platform", env: "PLATFORM", field: func(c *Config) any { return &c.Platform }},
+	{key: "server.addr", env: "HTTP_ADDR", field: func(c *Config) any { return &c.Server.Addr }},
+	{key: "server.read_header_timeout", env: "HTTP_READ_HEADER_TIMEOUT", field: func(c *Config) any { return &c.Server.ReadHeaderTimeout }},
+	{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", field: func(c *Config) any { return &c.Server.ReadTimeout }},
+	{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", field: func(c *Config) any { return &c.Server.WriteTimeout }},
+	{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", field: func(c *Config) any { return &c.Server.IdleTimeout }},
+	{key: "server.max_header_bytes", env: "HTTP_MAX_HEADER_BYTES", field: func(c *Config) any { return &c.Server.MaxHeaderBytes }},
+	{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", field: func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{key: "server.shutdown_delay", env: "SHUTDOWN_DELAY", field: func(c *Config) any { return &c.Server.ShutdownDelay }},
+	{
+		key: "database.url", env: "DB_URL",
+		field: func(c *Config) any { return &c.Database.URL },
+		file:  func(c *Config) *string { return &c.Database.URLFile },
+	},
+	{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", field: func(c *Config) any { return &c.Database.AutoMigrate }},
+	{key: "database.max_conns", env: "DB_MAX_CONNS", field: func(c *Config) any { return &c.Database.MaxConns }},
+	{key: "database.max_conn_idle_time", env: "DB_MAX_CONN_IDLE_TIME", field: func(c *Config) any { return &c.Database.MaxConnIdleTime }},
+	{key: "database.statement_cache_capacity", env: "DB_STATEMENT_CACHE_CAPACITY", field: func(c *Config) any { return &c.Database.StatementCacheCapacity }},
+	{
+		key: "auth.jwt_secret", env: "JWT_SECRET",
+		field: func(c *Config) any { return &c.Auth.JWTSecret },
+		file:  func(c *Config) *string { return &c.Auth.JWTSecretFile },
+	},
+	{key: "auth.argon2.memory_kib", env: "ARGON2_MEMORY_KIB", field: func(c *Config) any { return &c.Auth.Argon2.MemoryKiB }},
+	{key: "auth.argon2.iterations", env: "ARGON2_ITERATIONS", field: func(c *Config) any { return &c.Auth.Argon2.Iterations }},
+	{key: "auth.argon2.parallelism", env: "ARGON2_PARALLELISM", field: func(c *Config) any { return &c.Auth.Argon2.Parallelism }},
+	{key: "auth.password.min_length", env: "PASSWORD_MIN_LENGTH", field: func(c *Config) any { return &c.Auth.Password.MinLength }},
+	{key: "auth.password.min_entropy_bits", env: "PASSWORD_MIN_ENTROPY_BITS", field: func(c *Config) any { return &c.Auth.Password.MinEntropyBits }},
+	{key: "auth.lockout.account.threshold", env: "LOGIN_ACCOUNT_LOCKOUT_THRESHOLD", field: func(c *Config) any { return &c.Auth.Lockout.Account.Threshold }},
+	{key: "auth.lockout.account.base_delay", env: "LOGIN_ACCOUNT_LOCKOUT_BASE_DELAY", field: func(c *Config) any { return &c.Auth.Lockout.Account.BaseDelay }},
+	{key: "auth.lockout.account.max_delay", env: "LOGIN_ACCOUNT_LOCKOUT_MAX_DELAY", field: func(c *Config) any { return &c.Auth.Lockout.Account.MaxDelay }},
+	{key: "auth.lockout.account.window", env: "LOGIN_ACCOUNT_LOCKOUT_WINDOW", field: func(c *Config) any { return &c.Auth.Lockout.Account.Window }},
+	{key: "auth.lockout.ip.threshold", env: "LOGIN_IP_LOCKOUT_THRESHOLD", field: func(c *Config) any { return &c.Auth.Lockout.IP.Threshold }},
+	{key: "auth.lockout.ip.base_delay", env: "LOGIN_IP_LOCKOUT_BASE_DELAY", field: func(c *Config) any { return &c.Auth.Lockout.IP.BaseDelay }},
+	{key: "auth.lockout.ip.max_delay", env: "LOGIN_IP_LOCKOUT_MAX_DELAY", field: func(c *Config) any { return &c.Auth.Lockout.IP.MaxDelay }},
+	{key: "auth.lockout.ip.window", env: "LOGIN_IP_LOCKOUT_WINDOW", field: func(c *Config) any { return &c.Auth.Lockout.IP.Window }},
+	{
+		key: "polka.key", env: "POLKA_KEY",
+		field: func(c *Config) any { return &c.Polka.Key },
+		file:  func(c *Config) *string { return &c.Polka.KeyFile },
+	},
+	{
+		key: "polka.webhook_secret", env: "POLKA_WEBHOOK_SECRET",
+		field: func(c *Config) any { return &c.Polka.WebhookSecret },
+		file:  func(c *Config) *string { return &c.Polka.WebhookSecretFile },
+	},
+	{key: "log.level", env: "LOG_LEVEL", field: func(c *Config) any { return &c.Log.Level }},
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", field: func(c *Config) any { return &c.Tracing.Exporter }},
+}
+
+func (s setting) secret() bool {
+	return s.file != nil
+}
+
+// flagName derives the flag from the variable, e.g. HTTP_ADDR is --http-addr.
+func (s setting) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(s.env, "_", "-"))
+}
+
+func (s setting) applyEnv(c *Config, getenv func(string) string) error {
+	if s.secret() {
+		return applySecretEnv(s.env, s.field(c).(*Secret), s.file(c), getenv)
+	}
+	v := getenv(s.env)
+	if v == "" {
+		return nil
+	}
+	if err := setValue(s.field(c), v); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", s.env, v, err)
+	}
+	return nil
+}
+
+// setValue parses v into the field dst points to.
+func setValue(dst any, v string) error {
+	switch dst := dst.(type) {
+	case *string:
+		*dst = v
+	case *Secret:
+		*dst = Secret(v)
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*dst = b
+	case *Duration:
+		return dst.UnmarshalText([]byte(v))
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		*dst = n
+	case *uint32:
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return err
+		}
+		*dst = uint32(n)
+	case *uint8:
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			return err
+		}
+		*dst = uint8(n)
+	case *float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		if f < 0 {
+			return fmt.Errorf("must not be negative")
+		}
+		*dst = f
+	case *slog.Level:
+		return dst.UnmarshalText([]byte(v))
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %T", dst))
+	}
+	return nil
+}
+
+// Secret is a string that is redacted when printed or marshalled, so it can
+// not leak through --print-config or a stray log line.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+// Value returns the secret itself.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is needed alongside MarshalText so decoders take the value
+// verbatim.
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
+
+// Duration is a time.Duration written as a string such as "15s" in config
+// files.
+type Duration time.Duration
+
+// Std returns d as a time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	*d = Duration(parsed)
+	return nil
+}
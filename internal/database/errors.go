@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Classes of database error that callers handle the same way on every
+// backend. Match them with errors.Is on an error returned by a Querier from
+// Wrap, or on the result of Classify.
+var (
+	// ErrNotFound means a row the statement read or referenced does not
+	// exist. It covers sql.ErrNoRows and foreign key violations.
+	ErrNotFound = errors.New("database: not found")
+	// ErrConflict means the statement violated a unique constraint.
+	ErrConflict = errors.New("database: conflict")
+	// ErrSerialization means the statement lost a race with a concurrent
+	// transaction (a serialization failure or deadlock) and may succeed if
+	// retried.
+	ErrSerialization = errors.New("database: serialization failure")
+	// ErrTimeout means the statement ran out of time, waiting on a lock or
+	// for its context deadline.
+	ErrTimeout = errors.New("database: timeout")
+)
+
+// Error is a classified database error. errors.Is matches both its class
+// and the driver error it wraps, so sql.ErrNoRows checks keep working.
+type Error struct {
+	Class error
+	// Constraint names the violated constraint, using PostgreSQL's default
+	// names on every backend (users_email_key, refresh_tokens_pkey), when
+	// the driver reports it.
+	Constraint string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Class, e.Err}
+}
+
+// IsConflict reports whether err is a conflict on the named constraint.
+func IsConflict(err error, constraint string) bool {
+	var dbErr *Error
+	return errors.As(Classify(err), &dbErr) && dbErr.Class == ErrConflict && dbErr.Constraint == constraint
+}
+
+// Classify wraps a driver error in an *Error when it falls into one of the
+// classes above, and returns any other error unchanged.
+func Classify(err error) error {
+	var dbErr *Error
+	if err == nil || errors.As(err, &dbErr) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &Error{Class: ErrNotFound, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Class: ErrTimeout, Err: err}
+	case errors.As(err, &pgErr):
+		if class := postgresClass(pgErr.Code); class != nil {
+			return &Error{Class: class, Constraint: pgErr.ConstraintName, Err: err}
+		}
+	case errors.As(err, &sqliteErr):
+		if class := sqliteClass(sqliteErr); class != nil {
+			return &Error{Class: class, Constraint: sqliteConstraint(sqliteErr), Err: err}
+		}
+	}
+	return err
+}
+
+func postgresClass(code string) error {
+	switch code {
+	case "23505": // unique_violation
+		return ErrConflict
+	case "23503": // foreign_key_violation
+		return ErrNotFound
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return ErrSerialization
+	case "57014", "55P03": // query_canceled (statement_timeout), lock_not_available
+		return ErrTimeout
+	}
+	return nil
+}
+
+func sqliteClass(err sqlite3.Error) error {
+	switch {
+	case err.ExtendedCode == sqlite3.ErrConstraintUnique, err.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return ErrConflict
+	case err.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		return ErrNotFound
+	case err.ExtendedCode == sqlite3.ErrBusySnapshot, err.Code == sqlite3.ErrLocked:
+		return ErrSerialization
+	case err.Code == sqlite3.ErrBusy:
+		// The busy timeout has already elapsed.
+		return ErrTimeout
+	}
+	return nil
+}
+
+// sqliteConstraint recovers a PostgreSQL-style constraint name from a
+// message such as "UNIQUE constraint failed: users.email".
+func sqliteConstraint(err sqlite3.Error) string {
+	_, columns, ok := strings.Cut(err.Error(), "constraint failed: ")
+	if !ok {
+		return ""
+	}
+
+	var table string
+	var names []string
+	for column := range strings.SplitSeq(columns, ", ") {
+		t, c, _ := strings.Cut(column, ".")
+		table = t
+		names = append(names, c)
+	}
+	if err.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return table + "_pkey"
+	}
+	return table + "_" + strings.Join(names, "_") + "_key"
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		class      error
+		constraint string
+	}{
+		{name: "no rows", err: sql.ErrNoRows, class: ErrNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("loading user: %w", sql.ErrNoRows), class: ErrNotFound},
+		{name: "deadline", err: context.DeadlineExceeded, class: ErrTimeout},
+		{name: "postgres unique", err: &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, class: ErrConflict, constraint: "users_email_key"},
+		{name: "postgres foreign key", err: &pgconn.PgError{Code: "23503", ConstraintName: "chirps_user_id_fkey"}, class: ErrNotFound, constraint: "chirps_user_id_fkey"},
+		{name: "postgres serialization", err: &pgconn.PgError{Code: "40001"}, class: ErrSerialization},
+		{name: "postgres deadlock", err: &pgconn.PgError{Code: "40P01"}, class: ErrSerialization},
+		{name: "postgres statement timeout", err: &pgconn.PgError{Code: "57014"}, class: ErrTimeout},
+		{name: "postgres check", err: &pgconn.PgError{Code: "23514"}},
+		{name: "sqlite unique", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, class: ErrConflict},
+		{name: "sqlite foreign key", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey}, class: ErrNotFound},
+		{name: "sqlite busy snapshot", err: sqlite3.Error{Code: sqlite3.ErrBusy, ExtendedCode: sqlite3.ErrBusySnapshot}, class: ErrSerialization},
+		{name: "sqlite busy", err: sqlite3.Error{Code: sqlite3.ErrBusy, ExtendedCode: sqlite3.ErrBusy.Extend(0)}, class: ErrTimeout},
+		{name: "other", err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("Classify() = %v, lost the driver error", got)
+			}
+
+			var dbErr *Error
+			if !errors.As(got, &dbErr) {
+				if tt.class != nil {
+					t.Fatalf("Classify() = %v, want class %v", got, tt.class)
+				}
+				return
+			}
+			if dbErr.Class != tt.class || dbErr.Constraint != tt.constraint {
+				t.Errorf("Classify() = %v (%q), want %v (%q)", dbErr.Class, dbErr.Constraint, tt.class, tt.constraint)
+			}
+			if Classify(got) != got {
+				t.Error("classifying twice wrapped the error again")
+			}
+		})
+	}
+
+	if !IsConflict(&pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, "users_email_key") {
+		t.Error("IsConflict() = false for a conflict on the named constraint")
+	}
+	if IsConflict(&pgconn.PgError{Code: "23505", ConstraintName: "refresh_tokens_pkey"}, "users_email_key") {
+		t.Error("IsConflict() = true for a conflict on another constraint")
+	}
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CountChirpPostsSince(ctx context.Context, arg db.CountChirpPostsSinceParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, p := range s.chirpPosts {
+		if p.UserID == arg.UserID && !p.CreatedAt.Before(arg.CreatedAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateChirpPost(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID) {
+		return foreignKeyViolation("chirp_posts", "chirp_posts_user_id_fkey")
+	}
+	s.chirpPosts = append(s.chirpPosts, db.ChirpPost{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CreatedAt: s.timestamp(),
+	})
+	return nil
+}
+
+func (s *Store) DeleteChirpPostsBefore(ctx context.Context, arg db.DeleteChirpPostsBeforeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chirpPosts = slices.DeleteFunc(s.chirpPosts, func(p db.ChirpPost) bool {
+		return p.UserID == arg.UserID && p.CreatedAt.Before(arg.CreatedAt)
+	})
+	return nil
+}
+
+func (s *Store) CreateScheduledChirp(ctx context.Context, arg db.CreateScheduledChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chirpIndex(arg.ChirpID) < 0 {
+		return foreignKeyViolation("scheduled_chirps", "scheduled_chirps_chirp_id_fkey")
+	}
+	if _, ok := s.scheduled[arg.ChirpID]; ok {
+		return uniqueViolation("scheduled_chirps", "scheduled_chirps_pkey")
+	}
+	s.scheduled[arg.ChirpID] = db.ScheduledChirp{
+		ChirpID:   arg.ChirpID,
+		PublishAt: timestamp(arg.PublishAt),
+	}
+	return nil
+}
+
+// ClaimPublishedChirps removes and returns up to maxChirps scheduled chirps
+// whose publish_at has passed, earliest first.
+func (s *Store) ClaimPublishedChirps(ctx context.Context, maxChirps int32) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	var due []db.ScheduledChirp
+	for _, sc := range s.scheduled {
+		if !sc.PublishAt.After(now) {
+			due = append(due, sc)
+		}
+	}
+	slices.SortFunc(due, func(a, b db.ScheduledChirp) int { return a.PublishAt.Compare(b.PublishAt) })
+
+	var ids []uuid.UUID
+	for _, sc := range due[:min(len(due), int(maxChirps))] {
+		delete(s.scheduled, sc.ChirpID)
+		ids = append(ids, sc.ChirpID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) chirpIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.chirps, func(c db.Chirp) bool { return c.ID == id })
+}
+
+// sortedChirps returns the chirps matching keep ordered by created_at, ties
+// in insertion order.
+func (s *Store) sortedChirps(keep func(db.Chirp) bool) []db.Chirp {
+	var out []db.Chirp
+	for _, c := range s.chirps {
+		if keep(c) {
+			out = append(out, c)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b db.Chirp) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out
+}
+
+func (s *Store) CreateChirp(ctx context.Context, arg db.CreateChirpParams) (db.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return db.Chirp{}, foreignKeyViolation("chirps", "chirps_user_id_fkey")
+	}
+	now := s.timestamp()
+	c := db.Chirp{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		PublishedAt: now,
+	}
+	if arg.PublishedAt.Valid {
+		c.PublishedAt = timestamp(arg.PublishedAt.Time)
+	}
+	s.chirps = append(s.chirps, c)
+	return c, nil
+}
+
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.chirpIndex(id); i >= 0 {
+		s.chirps = slices.Delete(s.chirps, i, i+1)
+	}
+	delete(s.scheduled, id)
+	return nil
+}
+
+func (s *Store) GetChirp(ctx context.Context, id uuid.UUID) (db.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.chirpIndex(id)
+	if i < 0 {
+		return db.Chirp{}, sql.ErrNoRows
+	}
+	return s.chirps[i], nil
+}
+
+func (s *Store) ListChirps(ctx context.Context) ([]db.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedChirps(func(db.Chirp) bool { return true }), nil
+}
+
+func (s *Store) ListChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]db.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedChirps(func(c db.Chirp) bool { return c.UserID == userID }), nil
+}
+
+func (s *Store) UpdateChirpBody(ctx context.Context, arg db.UpdateChirpBodyParams) (db.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.chirpIndex(arg.ID)
+	if i < 0 {
+		return db.Chirp{}, sql.ErrNoRows
+	}
+	s.chirps[i].Body = arg.Body
+	s.chirps[i].UpdatedAt = s.timestamp()
+	return s.chirps[i], nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) DeleteUserEntitlement(ctx context.Context, arg db.DeleteUserEntitlementParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := entitlementKey{arg.UserID, arg.Feature}
+	if _, ok := s.entitlements[key]; !ok {
+		return 0, nil
+	}
+	delete(s.entitlements, key)
+	return 1, nil
+}
+
+func (s *Store) ListUserEntitlements(ctx context.Context, userID uuid.UUID) ([]db.UserEntitlement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []db.UserEntitlement
+	for key, e := range s.entitlements {
+		if key.userID == userID {
+			out = append(out, e)
+		}
+	}
+	slices.SortFunc(out, func(a, b db.UserEntitlement) int { return strings.Compare(a.Feature, b.Feature) })
+	return out, nil
+}
+
+func (s *Store) UpsertUserEntitlement(ctx context.Context, arg db.UpsertUserEntitlementParams) (db.UserEntitlement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return db.UserEntitlement{}, foreignKeyViolation("user_entitlements", "user_entitlements_user_id_fkey")
+	}
+	if arg.GrantedBy.Valid && !s.userExists(arg.GrantedBy.UUID) {
+		return db.UserEntitlement{}, foreignKeyViolation("user_entitlements", "user_entitlements_granted_by_fkey")
+	}
+
+	now := s.timestamp()
+	key := entitlementKey{arg.UserID, arg.Feature}
+	e, ok := s.entitlements[key]
+	if !ok {
+		e = db.UserEntitlement{UserID: arg.UserID, Feature: arg.Feature, CreatedAt: now}
+	}
+	e.Granted = arg.Granted
+	e.GrantedBy = arg.GrantedBy
+	e.UpdatedAt = now
+	s.entitlements[key] = e
+	return e, nil
+}
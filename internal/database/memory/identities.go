@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	db "chirpy/internal/database"
+)
+
+func (s *Store) ConsumeOIDCLoginState(ctx context.Context, state string) (db.OidcLoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.oidcStates[state]
+	if !ok {
+		return db.OidcLoginState{}, sql.ErrNoRows
+	}
+	delete(s.oidcStates, state)
+	return st, nil
+}
+
+func (s *Store) CreateOIDCLoginState(ctx context.Context, arg db.CreateOIDCLoginStateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.oidcStates[arg.State]; ok {
+		return uniqueViolation("oidc_login_states", "oidc_login_states_pkey")
+	}
+	if arg.LinkUserID.Valid && !s.userExists(arg.LinkUserID.UUID) {
+		return foreignKeyViolation("oidc_login_states", "oidc_login_states_link_user_id_fkey")
+	}
+	s.oidcStates[arg.State] = db.OidcLoginState{
+		State:        arg.State,
+		Provider:     arg.Provider,
+		Nonce:        arg.Nonce,
+		CodeVerifier: arg.CodeVerifier,
+		LinkUserID:   arg.LinkUserID,
+		UseCookies:   arg.UseCookies,
+		CreatedAt:    s.timestamp(),
+		ExpiresAt:    timestamp(arg.ExpiresAt),
+	}
+	return nil
+}
+
+func (s *Store) CreateUserIdentity(ctx context.Context, arg db.CreateUserIdentityParams) (db.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := identityKey{arg.Provider, arg.Subject}
+	if _, ok := s.identities[key]; ok {
+		return db.UserIdentity{}, uniqueViolation("user_identities", "user_identities_pkey")
+	}
+	if !s.userExists(arg.UserID) {
+		return db.UserIdentity{}, foreignKeyViolation("user_identities", "user_identities_user_id_fkey")
+	}
+	now := s.timestamp()
+	identity := db.UserIdentity{
+		Provider:  arg.Provider,
+		Subject:   arg.Subject,
+		UserID:    arg.UserID,
+		Email:     arg.Email,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.identities[key] = identity
+	return identity, nil
+}
+
+func (s *Store) DeleteExpiredOIDCLoginStates(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for state, st := range s.oidcStates {
+		if st.ExpiresAt.Before(now) {
+			delete(s.oidcStates, state)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (db.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.identities[identityKey{arg.Provider, arg.Subject}]
+	if !ok {
+		return db.UserIdentity{}, sql.ErrNoRows
+	}
+	return identity, nil
+}
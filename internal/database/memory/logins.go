@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) ClearLoginThrottle(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.throttles, key)
+	return nil
+}
+
+func (s *Store) CreateLoginAttempt(ctx context.Context, arg db.CreateLoginAttemptParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.UserID.Valid && !s.userExists(arg.UserID.UUID) {
+		return foreignKeyViolation("login_attempts", "login_attempts_user_id_fkey")
+	}
+	s.loginAttempts = append(s.loginAttempts, db.LoginAttempt{
+		ID:            uuid.New(),
+		CreatedAt:     s.timestamp(),
+		Email:         arg.Email,
+		UserID:        arg.UserID,
+		IpAddress:     arg.IpAddress,
+		UserAgent:     arg.UserAgent,
+		Succeeded:     arg.Succeeded,
+		FailureReason: arg.FailureReason,
+	})
+	return nil
+}
+
+func (s *Store) ForgiveLoginFailure(ctx context.Context, key string) (db.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[key]
+	if !ok || t.Failures <= 0 {
+		return db.LoginThrottle{}, sql.ErrNoRows
+	}
+	t.Failures--
+	s.throttles[key] = t
+	return t, nil
+}
+
+func (s *Store) GetLoginThrottle(ctx context.Context, key string) (db.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[key]
+	if !ok {
+		return db.LoginThrottle{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+// LockLoginThrottle returns the throttle for key, creating it with no
+// failures if needed. There are no row locks; InTx already runs
+// transactions one at a time.
+func (s *Store) LockLoginThrottle(ctx context.Context, key string) (db.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[key]
+	if !ok {
+		t = db.LoginThrottle{Key: key, UpdatedAt: s.timestamp()}
+		s.throttles[key] = t
+	}
+	return t, nil
+}
+
+// RecordLoginFailure counts a failure, restarting the count when the previous
+// one is older than WindowStart. An existing lockout is left in place.
+func (s *Store) RecordLoginFailure(ctx context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[arg.Key]
+	switch {
+	case !ok:
+		t = db.LoginThrottle{Key: arg.Key, Failures: 1}
+	case t.UpdatedAt.Before(timestamp(arg.WindowStart)):
+		t.Failures = 1
+	default:
+		t.Failures++
+	}
+	t.UpdatedAt = s.timestamp()
+	s.throttles[arg.Key] = t
+	return t, nil
+}
+
+func (s *Store) SetLoginLockout(ctx context.Context, arg db.SetLoginLockoutParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.throttles[arg.Key]; ok {
+		t.LockedUntil = nullTimestamp(arg.LockedUntil)
+		s.throttles[arg.Key] = t
+	}
+	return nil
+}
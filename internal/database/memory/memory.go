@@ -0,0 +1,209 @@
+// Package memory is an in-memory implementation of database.Querier for
+// tests. It follows the Postgres schema closely enough that handlers behave
+// the same: missing rows are sql.ErrNoRows, and unique, foreign key and check
+// violations are *pgconn.PgError values carrying the Postgres code and constraint
+// name. Deleting users cascades the way the schema's foreign keys do.
+//
+// Timestamps are stored in UTC at microsecond precision, like the TIMESTAMP
+// columns they stand in for.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes returned by Store.
+const (
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+)
+
+type entitlementKey struct {
+	userID  uuid.UUID
+	feature string
+}
+
+type identityKey struct {
+	provider string
+	subject  string
+}
+
+type webhookEventKey struct {
+	source string
+	id     string
+}
+
+// Store is safe for concurrent use. The zero value is not usable; call New.
+type Store struct {
+	mu  sync.Mutex
+	now func() time.Time
+	// txMu serializes transactions, see InTx.
+	txMu sync.Mutex
+	tables
+}
+
+// tables holds the rows of every table.
+type tables struct {
+	users         map[uuid.UUID]db.User
+	chirps        []db.Chirp
+	chirpPosts    []db.ChirpPost
+	scheduled     map[uuid.UUID]db.ScheduledChirp
+	refreshTokens map[string]db.RefreshToken
+	entitlements  map[entitlementKey]db.UserEntitlement
+	identities    map[identityKey]db.UserIdentity
+	oidcStates    map[string]db.OidcLoginState
+	throttles     map[string]db.LoginThrottle
+	loginAttempts []db.LoginAttempt
+	oauthClients  []db.OauthClient
+	authCodes     map[string]db.OauthAuthorizationCode
+	subscriptions map[uuid.UUID]db.Subscription
+	endpoints     []db.WebhookEndpoint
+	deliveries    []db.WebhookDelivery
+	webhookEvents []db.WebhookEvent
+}
+
+// clone copies t so that later writes to t leave the copy unchanged. Rows
+// are values whose slices are never modified in place, so copying the
+// containers is enough.
+func (t tables) clone() tables {
+	return tables{
+		users:         maps.Clone(t.users),
+		chirps:        slices.Clone(t.chirps),
+		chirpPosts:    slices.Clone(t.chirpPosts),
+		scheduled:     maps.Clone(t.scheduled),
+		refreshTokens: maps.Clone(t.refreshTokens),
+		entitlements:  maps.Clone(t.entitlements),
+		identities:    maps.Clone(t.identities),
+		oidcStates:    maps.Clone(t.oidcStates),
+		throttles:     maps.Clone(t.throttles),
+		loginAttempts: slices.Clone(t.loginAttempts),
+		oauthClients:  slices.Clone(t.oauthClients),
+		authCodes:     maps.Clone(t.authCodes),
+		subscriptions: maps.Clone(t.subscriptions),
+		endpoints:     slices.Clone(t.endpoints),
+		deliveries:    slices.Clone(t.deliveries),
+		webhookEvents: slices.Clone(t.webhookEvents),
+	}
+}
+
+var (
+	_ db.Querier    = (*Store)(nil)
+	_ db.Transactor = (*Store)(nil)
+)
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		now: time.Now,
+		tables: tables{
+			users:         make(map[uuid.UUID]db.User),
+			scheduled:     make(map[uuid.UUID]db.ScheduledChirp),
+			refreshTokens: make(map[string]db.RefreshToken),
+			entitlements:  make(map[entitlementKey]db.UserEntitlement),
+			identities:    make(map[identityKey]db.UserIdentity),
+			oidcStates:    make(map[string]db.OidcLoginState),
+			throttles:     make(map[string]db.LoginThrottle),
+			authCodes:     make(map[string]db.OauthAuthorizationCode),
+			subscriptions: make(map[uuid.UUID]db.Subscription),
+		},
+	}
+}
+
+// InTx runs fn against the store and undoes its writes if it returns an
+// error. Transactions run one at a time, but statements outside a
+// transaction are not isolated from one in progress.
+func (s *Store) InTx(ctx context.Context, fn func(db.Querier) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	saved := s.tables.clone()
+	s.mu.Unlock()
+
+	if err := fn(db.Wrap(s, db.RetryPolicy{})); err != nil {
+		s.mu.Lock()
+		s.tables = saved
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// SetNow replaces the clock used for NOW(), so tests can move time forward.
+func (s *Store) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (s *Store) timestamp() time.Time {
+	return timestamp(s.now())
+}
+
+func timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func nullTimestamp(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = timestamp(t.Time)
+	}
+	return t
+}
+
+func uniqueViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Code:           codeUniqueViolation,
+		Message:        `duplicate key value violates unique constraint "` + constraint + `"`,
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Code:           codeForeignKeyViolation,
+		Message:        `insert or update on table "` + table + `" violates foreign key constraint "` + constraint + `"`,
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func checkViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Code:           codeCheckViolation,
+		Message:        `new row for relation "` + table + `" violates check constraint "` + constraint + `"`,
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// userExists reports whether id references a user. Callers hold s.mu.
+func (s *Store) userExists(id uuid.UUID) bool {
+	_, ok := s.users[id]
+	return ok
+}
+
+func cloneStrings(v []string) []string {
+	if v == nil {
+		return nil
+	}
+	return append([]string{}, v...)
+}
+
+func cloneBytes[T ~[]byte](v T) T {
+	if v == nil {
+		return nil
+	}
+	return append(T{}, v...)
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) oauthClientIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.oauthClients, func(c db.OauthClient) bool { return c.ID == id })
+}
+
+func cloneOAuthClient(c db.OauthClient) db.OauthClient {
+	c.RedirectUris = cloneStrings(c.RedirectUris)
+	c.Scopes = cloneStrings(c.Scopes)
+	return c
+}
+
+func (s *Store) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (db.OauthAuthorizationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.authCodes[codeHash]
+	if !ok {
+		return db.OauthAuthorizationCode{}, sql.ErrNoRows
+	}
+	delete(s.authCodes, codeHash)
+	return code, nil
+}
+
+func (s *Store) CreateOAuthAuthorizationCode(ctx context.Context, arg db.CreateOAuthAuthorizationCodeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.authCodes[arg.CodeHash]; ok {
+		return uniqueViolation("oauth_authorization_codes", "oauth_authorization_codes_pkey")
+	}
+	if s.oauthClientIndex(arg.ClientID) < 0 {
+		return foreignKeyViolation("oauth_authorization_codes", "oauth_authorization_codes_client_id_fkey")
+	}
+	if !s.userExists(arg.UserID) {
+		return foreignKeyViolation("oauth_authorization_codes", "oauth_authorization_codes_user_id_fkey")
+	}
+	s.authCodes[arg.CodeHash] = db.OauthAuthorizationCode{
+		CodeHash:      arg.CodeHash,
+		ClientID:      arg.ClientID,
+		UserID:        arg.UserID,
+		RedirectUri:   arg.RedirectUri,
+		Scope:         arg.Scope,
+		CodeChallenge: arg.CodeChallenge,
+		CreatedAt:     s.timestamp(),
+		ExpiresAt:     timestamp(arg.ExpiresAt),
+	}
+	return nil
+}
+
+func (s *Store) CreateOAuthClient(ctx context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.OwnerID) {
+		return db.OauthClient{}, foreignKeyViolation("oauth_clients", "oauth_clients_owner_id_fkey")
+	}
+	now := s.timestamp()
+	c := db.OauthClient{
+		ID:           uuid.New(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		OwnerID:      arg.OwnerID,
+		Name:         arg.Name,
+		SecretHash:   arg.SecretHash,
+		RedirectUris: cloneStrings(arg.RedirectUris),
+		Scopes:       cloneStrings(arg.Scopes),
+	}
+	s.oauthClients = append(s.oauthClients, c)
+	return cloneOAuthClient(c), nil
+}
+
+func (s *Store) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for hash, code := range s.authCodes {
+		if code.ExpiresAt.Before(now) {
+			delete(s.authCodes, hash)
+		}
+	}
+	return nil
+}
+
+// DeleteOAuthClient also removes the client's authorization codes and
+// refresh tokens, as the schema's cascading foreign keys do.
+func (s *Store) DeleteOAuthClient(ctx context.Context, arg db.DeleteOAuthClientParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.oauthClientIndex(arg.ID)
+	if i < 0 || s.oauthClients[i].OwnerID != arg.OwnerID {
+		return 0, nil
+	}
+	s.oauthClients = slices.Delete(s.oauthClients, i, i+1)
+	for hash, code := range s.authCodes {
+		if code.ClientID == arg.ID {
+			delete(s.authCodes, hash)
+		}
+	}
+	for token, t := range s.refreshTokens {
+		if t.ClientID.Valid && t.ClientID.UUID == arg.ID {
+			delete(s.refreshTokens, token)
+		}
+	}
+	return 1, nil
+}
+
+func (s *Store) GetOAuthClient(ctx context.Context, id uuid.UUID) (db.OauthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.oauthClientIndex(id)
+	if i < 0 {
+		return db.OauthClient{}, sql.ErrNoRows
+	}
+	return cloneOAuthClient(s.oauthClients[i]), nil
+}
+
+func (s *Store) ListOAuthClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]db.OauthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []db.OauthClient
+	for _, c := range s.oauthClients {
+		if c.OwnerID == ownerID {
+			out = append(out, cloneOAuthClient(c))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b db.OauthClient) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	db "chirpy/internal/database"
+)
+
+func (s *Store) insertRefreshToken(t db.RefreshToken) (db.RefreshToken, error) {
+	if _, ok := s.refreshTokens[t.Token]; ok {
+		return db.RefreshToken{}, uniqueViolation("refresh_tokens", "refresh_tokens_pkey")
+	}
+	if !s.userExists(t.UserID) {
+		return db.RefreshToken{}, foreignKeyViolation("refresh_tokens", "refresh_tokens_user_id_fkey")
+	}
+	if t.ClientID.Valid && s.oauthClientIndex(t.ClientID.UUID) < 0 {
+		return db.RefreshToken{}, foreignKeyViolation("refresh_tokens", "refresh_tokens_client_id_fkey")
+	}
+	now := s.timestamp()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.ExpiresAt = timestamp(t.ExpiresAt)
+	s.refreshTokens[t.Token] = t
+	return t, nil
+}
+
+func (s *Store) CreateOAuthRefreshToken(ctx context.Context, arg db.CreateOAuthRefreshTokenParams) (db.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertRefreshToken(db.RefreshToken{
+		Token:     arg.Token,
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+		ClientID:  arg.ClientID,
+		Scope:     arg.Scope,
+	})
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg db.CreateRefreshTokenParams) (db.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertRefreshToken(db.RefreshToken{
+		Token:     arg.Token,
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+	})
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, token string) (db.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refreshTokens[token]
+	if !ok {
+		return db.RefreshToken{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+func (s *Store) GetUserFromRefreshToken(ctx context.Context, token string) (db.GetUserFromRefreshTokenRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refreshTokens[token]
+	if !ok {
+		return db.GetUserFromRefreshTokenRow{}, sql.ErrNoRows
+	}
+	u := s.users[t.UserID]
+	return db.GetUserFromRefreshTokenRow{
+		UserID:             u.ID,
+		UserCreatedAt:      u.CreatedAt,
+		UserUpdatedAt:      u.UpdatedAt,
+		UserEmail:          u.Email,
+		UserHashedPassword: u.HashedPassword,
+		UserRole:           u.Role,
+		Token:              t.Token,
+		CreatedAt:          t.CreatedAt,
+		UpdatedAt:          t.UpdatedAt,
+		ExpiresAt:          t.ExpiresAt,
+		RevokedAt:          t.RevokedAt,
+		ClientID:           t.ClientID,
+		Scope:              t.Scope,
+	}, nil
+}
+
+func (s *Store) RevokeActiveRefreshToken(ctx context.Context, token string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refreshTokens[token]
+	if !ok || t.RevokedAt.Valid {
+		return 0, nil
+	}
+	s.revoke(t)
+	return 1, nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.refreshTokens[token]; ok {
+		s.revoke(t)
+	}
+	return nil
+}
+
+func (s *Store) revoke(t db.RefreshToken) {
+	now := s.timestamp()
+	t.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	t.UpdatedAt = now
+	s.refreshTokens[t.Token] = t
+}
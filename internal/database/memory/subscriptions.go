@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var validSubscriptionStatuses = map[string]bool{
+	"active": true, "past_due": true, "canceled": true, "downgraded": true, "refunded": true,
+}
+
+func (s *Store) GetSubscription(ctx context.Context, userID uuid.UUID) (db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[userID]
+	if !ok {
+		return db.Subscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (s *Store) UpsertSubscription(ctx context.Context, arg db.UpsertSubscriptionParams) (db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validSubscriptionStatuses[arg.Status] {
+		return db.Subscription{}, checkViolation("subscriptions", "subscriptions_status_check")
+	}
+	if !s.userExists(arg.UserID) {
+		return db.Subscription{}, foreignKeyViolation("subscriptions", "subscriptions_user_id_fkey")
+	}
+
+	now := s.timestamp()
+	sub, ok := s.subscriptions[arg.UserID]
+	if !ok {
+		sub = db.Subscription{UserID: arg.UserID, CreatedAt: now}
+	}
+	sub.Status = arg.Status
+	sub.CurrentPeriodStart = timestamp(arg.CurrentPeriodStart)
+	sub.CurrentPeriodEnd = timestamp(arg.CurrentPeriodEnd)
+	sub.UpdatedAt = now
+	s.subscriptions[arg.UserID] = sub
+	return sub, nil
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var validRoles = map[string]bool{"user": true, "moderator": true, "admin": true}
+
+func (s *Store) emailTaken(email string, except uuid.UUID) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) CountUsersByRole(ctx context.Context, role string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, u := range s.users {
+		if u.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(arg.Email, uuid.Nil) {
+		return db.User{}, uniqueViolation("users", "users_email_key")
+	}
+	now := s.timestamp()
+	u := db.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Role:           "user",
+	}
+	s.users[u.ID] = u
+	return u, nil
+}
+
+// DeleteUsers removes every user along with the rows that reference them
+// through ON DELETE CASCADE foreign keys.
+func (s *Store) DeleteUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.users)
+	s.chirps = nil
+	s.chirpPosts = nil
+	clear(s.scheduled)
+	clear(s.refreshTokens)
+	clear(s.entitlements)
+	clear(s.identities)
+	for state, st := range s.oidcStates {
+		if st.LinkUserID.Valid {
+			delete(s.oidcStates, state)
+		}
+	}
+	s.oauthClients = nil
+	clear(s.authCodes)
+	clear(s.subscriptions)
+	s.endpoints = nil
+	s.deliveries = nil
+	for i := range s.loginAttempts {
+		s.loginAttempts[i].UserID = uuid.NullUUID{}
+	}
+	return nil
+}
+
+// LockUser does nothing: there are no row locks, and InTx already runs
+// transactions one at a time.
+func (s *Store) LockUser(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id uuid.UUID) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return db.User{}, sql.ErrNoRows
+}
+
+func (s *Store) SetUserRole(ctx context.Context, arg db.SetUserRoleParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[arg.ID]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	if !validRoles[arg.Role] {
+		return db.User{}, checkViolation("users", "users_role_check")
+	}
+	u.Role = arg.Role
+	u.UpdatedAt = s.timestamp()
+	s.users[u.ID] = u
+	return u, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[arg.ID]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	if s.emailTaken(arg.Email, arg.ID) {
+		return db.User{}, uniqueViolation("users", "users_email_key")
+	}
+	u.Email = arg.Email
+	u.HashedPassword = arg.HashedPassword
+	u.UpdatedAt = s.timestamp()
+	s.users[u.ID] = u
+	return u, nil
+}
+
+func (s *Store) UpdateUserPasswordHash(ctx context.Context, arg db.UpdateUserPasswordHashParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[arg.ID]; ok {
+		u.HashedPassword = arg.HashedPassword
+		s.users[u.ID] = u
+	}
+	return nil
+}
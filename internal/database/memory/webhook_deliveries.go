@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var validDeliveryStatuses = map[string]bool{"pending": true, "succeeded": true, "failed": true}
+
+func (s *Store) deliveryIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.deliveries, func(d db.WebhookDelivery) bool { return d.ID == id })
+}
+
+func cloneDelivery(d db.WebhookDelivery) db.WebhookDelivery {
+	d.Payload = cloneBytes(d.Payload)
+	return d
+}
+
+// ClaimDueWebhookDeliveries leases up to MaxDeliveries pending deliveries
+// that are due, oldest first, by pushing their next attempt to LeaseUntil.
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, arg db.ClaimDueWebhookDeliveriesParams) ([]db.ClaimDueWebhookDeliveriesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	var due []int
+	for i, d := range s.deliveries {
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b int) int {
+		return s.deliveries[a].NextAttemptAt.Compare(s.deliveries[b].NextAttemptAt)
+	})
+	if len(due) > int(arg.MaxDeliveries) {
+		due = due[:max(arg.MaxDeliveries, 0)]
+	}
+
+	var out []db.ClaimDueWebhookDeliveriesRow
+	for _, i := range due {
+		d := &s.deliveries[i]
+		d.NextAttemptAt = timestamp(arg.LeaseUntil)
+		d.UpdatedAt = now
+		e := s.endpoints[s.endpointIndex(d.EndpointID)]
+		out = append(out, db.ClaimDueWebhookDeliveriesRow{
+			ID:        d.ID,
+			EventID:   d.EventID,
+			EventType: d.EventType,
+			Payload:   cloneBytes(d.Payload),
+			Attempts:  d.Attempts,
+			Url:       e.Url,
+			Secret:    e.Secret,
+		})
+	}
+	return out, nil
+}
+
+// EnqueueWebhookDeliveries queues the event for every endpoint subscribed to
+// its type that belongs to the user, or that listens to all users and whose
+// owner is still an admin.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, arg db.EnqueueWebhookDeliveriesParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	var count int64
+	for _, e := range s.endpoints {
+		if !slices.Contains(e.EventTypes, arg.EventType) || (e.OwnerID != arg.UserID && !(e.AllUsers && s.users[e.OwnerID].Role == "admin")) {
+			continue
+		}
+		s.deliveries = append(s.deliveries, db.WebhookDelivery{
+			ID:            uuid.New(),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+			EndpointID:    e.ID,
+			EventID:       arg.EventID,
+			EventType:     arg.EventType,
+			Payload:       cloneBytes(arg.Payload),
+			Status:        "pending",
+			NextAttemptAt: now,
+		})
+		count++
+	}
+	return count, nil
+}
+
+func (s *Store) ListWebhookDeliveriesByEndpoint(ctx context.Context, arg db.ListWebhookDeliveriesByEndpointParams) ([]db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []db.WebhookDelivery
+	for _, d := range slices.Backward(s.deliveries) {
+		if d.EndpointID == arg.EndpointID {
+			out = append(out, cloneDelivery(d))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b db.WebhookDelivery) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	if len(out) > int(arg.Limit) {
+		out = out[:max(arg.Limit, 0)]
+	}
+	return out, nil
+}
+
+func (s *Store) RecordWebhookDeliveryAttempt(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validDeliveryStatuses[arg.Status] {
+		return checkViolation("webhook_deliveries", "webhook_deliveries_status_check")
+	}
+	i := s.deliveryIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	now := s.timestamp()
+	d := &s.deliveries[i]
+	d.Status = arg.Status
+	d.Attempts++
+	d.NextAttemptAt = timestamp(arg.NextAttemptAt)
+	d.LastAttemptAt = sql.NullTime{Time: now, Valid: true}
+	d.LastStatusCode = arg.LastStatusCode
+	d.LastError = arg.LastError
+	d.UpdatedAt = now
+	return nil
+}
+
+// RedeliverWebhookDelivery resets a delivery to be sent again, provided its
+// endpoint belongs to OwnerID.
+func (s *Store) RedeliverWebhookDelivery(ctx context.Context, arg db.RedeliverWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.deliveryIndex(arg.ID)
+	if i < 0 {
+		return db.WebhookDelivery{}, sql.ErrNoRows
+	}
+	d := &s.deliveries[i]
+	if e := s.endpointIndex(d.EndpointID); e < 0 || s.endpoints[e].OwnerID != arg.OwnerID {
+		return db.WebhookDelivery{}, sql.ErrNoRows
+	}
+	now := s.timestamp()
+	d.Status = "pending"
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+	return cloneDelivery(*d), nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) endpointIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.endpoints, func(e db.WebhookEndpoint) bool { return e.ID == id })
+}
+
+func cloneEndpoint(e db.WebhookEndpoint) db.WebhookEndpoint {
+	e.EventTypes = cloneStrings(e.EventTypes)
+	return e
+}
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, arg db.CreateWebhookEndpointParams) (db.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.OwnerID) {
+		return db.WebhookEndpoint{}, foreignKeyViolation("webhook_endpoints", "webhook_endpoints_owner_id_fkey")
+	}
+	now := s.timestamp()
+	e := db.WebhookEndpoint{
+		ID:         uuid.New(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		OwnerID:    arg.OwnerID,
+		Url:        arg.Url,
+		Secret:     arg.Secret,
+		EventTypes: cloneStrings(arg.EventTypes),
+		AllUsers:   arg.AllUsers,
+	}
+	s.endpoints = append(s.endpoints, e)
+	return cloneEndpoint(e), nil
+}
+
+// DeleteWebhookEndpoint also removes the endpoint's deliveries.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, arg db.DeleteWebhookEndpointParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.endpointIndex(arg.ID)
+	if i < 0 || s.endpoints[i].OwnerID != arg.OwnerID {
+		return 0, nil
+	}
+	s.endpoints = slices.Delete(s.endpoints, i, i+1)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d db.WebhookDelivery) bool { return d.EndpointID == arg.ID })
+	return 1, nil
+}
+
+func (s *Store) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (db.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.endpointIndex(id)
+	if i < 0 {
+		return db.WebhookEndpoint{}, sql.ErrNoRows
+	}
+	return cloneEndpoint(s.endpoints[i]), nil
+}
+
+func (s *Store) ListWebhookEndpointsByOwner(ctx context.Context, ownerID uuid.UUID) ([]db.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []db.WebhookEndpoint
+	for _, e := range s.endpoints {
+		if e.OwnerID == ownerID {
+			out = append(out, cloneEndpoint(e))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b db.WebhookEndpoint) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	db "chirpy/internal/database"
+)
+
+func (s *Store) webhookEventIndex(source, id string) int {
+	return slices.IndexFunc(s.webhookEvents, func(e db.WebhookEvent) bool {
+		return webhookEventKey{e.Source, e.ID} == webhookEventKey{source, id}
+	})
+}
+
+func cloneWebhookEvent(e db.WebhookEvent) db.WebhookEvent {
+	e.Payload = cloneBytes(e.Payload)
+	return e
+}
+
+// ClaimWebhookEvent marks an unprocessed event as processed, returning 0 if
+// another delivery already did.
+func (s *Store) ClaimWebhookEvent(ctx context.Context, arg db.ClaimWebhookEventParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.webhookEventIndex(arg.Source, arg.ID)
+	if i < 0 || s.webhookEvents[i].ProcessedAt.Valid {
+		return 0, nil
+	}
+	e := &s.webhookEvents[i]
+	e.ProcessedAt = sql.NullTime{Time: s.timestamp(), Valid: true}
+	e.LastError = sql.NullString{}
+	return 1, nil
+}
+
+func (s *Store) FailWebhookEvent(ctx context.Context, arg db.FailWebhookEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookEventIndex(arg.Source, arg.ID); i >= 0 {
+		s.webhookEvents[i].ProcessedAt = sql.NullTime{}
+		s.webhookEvents[i].LastError = arg.LastError
+	}
+	return nil
+}
+
+func (s *Store) GetWebhookEvent(ctx context.Context, arg db.GetWebhookEventParams) (db.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.webhookEventIndex(arg.Source, arg.ID)
+	if i < 0 {
+		return db.WebhookEvent{}, sql.ErrNoRows
+	}
+	return cloneWebhookEvent(s.webhookEvents[i]), nil
+}
+
+// ListWebhookEvents returns the most recently received events first.
+func (s *Store) ListWebhookEvents(ctx context.Context, limit int32) ([]db.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []db.WebhookEvent
+	for _, e := range slices.Backward(s.webhookEvents) {
+		out = append(out, cloneWebhookEvent(e))
+	}
+	slices.SortStableFunc(out, func(a, b db.WebhookEvent) int { return b.ReceivedAt.Compare(a.ReceivedAt) })
+	if len(out) > int(limit) {
+		out = out[:max(limit, 0)]
+	}
+	return out, nil
+}
+
+func (s *Store) MarkWebhookEventReplayed(ctx context.Context, arg db.MarkWebhookEventReplayedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookEventIndex(arg.Source, arg.ID); i >= 0 {
+		e := &s.webhookEvents[i]
+		e.ProcessedAt = sql.NullTime{Time: s.timestamp(), Valid: true}
+		e.LastError = sql.NullString{}
+		e.Attempts++
+	}
+	return nil
+}
+
+// RecordWebhookEvent stores a newly received event, or counts another
+// delivery attempt of one already seen.
+func (s *Store) RecordWebhookEvent(ctx context.Context, arg db.RecordWebhookEventParams) (db.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookEventIndex(arg.Source, arg.ID); i >= 0 {
+		s.webhookEvents[i].Attempts++
+		return cloneWebhookEvent(s.webhookEvents[i]), nil
+	}
+	e := db.WebhookEvent{
+		Source:     arg.Source,
+		ID:         arg.ID,
+		EventType:  arg.EventType,
+		Payload:    cloneBytes(arg.Payload),
+		ReceivedAt: s.timestamp(),
+		Attempts:   1,
+	}
+	s.webhookEvents = append(s.webhookEvents, e)
+	return cloneWebhookEvent(e), nil
+}
+
+func (s *Store) RecordWebhookEventError(ctx context.Context, arg db.RecordWebhookEventErrorParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.webhookEventIndex(arg.Source, arg.ID); i >= 0 {
+		s.webhookEvents[i].LastError = arg.LastError
+	}
+	return nil
+}
@@ -6,17 +6,76 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	PublishedAt time.Time
+}
+
+type ChirpPost struct {
 	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type LoginAttempt struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	Email         string
+	UserID        uuid.NullUUID
+	IpAddress     string
+	UserAgent     string
+	Succeeded     bool
+	FailureReason sql.NullString
+}
+
+type LoginThrottle struct {
+	Key         string
+	Failures    int32
+	LockedUntil sql.NullTime
+	UpdatedAt   time.Time
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scope         string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+}
+
+type OidcLoginState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   uuid.NullUUID
+	UseCookies   bool
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
 }
 
 type RefreshToken struct {
@@ -26,6 +85,22 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  uuid.NullUUID
+	Scope     sql.NullString
+}
+
+type ScheduledChirp struct {
+	ChirpID   uuid.UUID
+	PublishAt time.Time
+}
+
+type Subscription struct {
+	UserID             uuid.UUID
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 type User struct {
@@ -34,5 +109,61 @@ type User struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
-	IsChirpyRed    bool
+	Role           string
+}
+
+type UserEntitlement struct {
+	UserID    uuid.UUID
+	Feature   string
+	Granted   bool
+	GrantedBy uuid.NullUUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type UserIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+}
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	OwnerID    uuid.UUID
+	Url        string
+	Secret     string
+	EventTypes []string
+	AllUsers   bool
+}
+
+type WebhookEvent struct {
+	Source      string
+	ID          string
+	EventType   string
+	Payload     json.RawMessage
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
+	Attempts    int32
+	LastError   sql.NullString
 }
@@ -13,6 +13,51 @@ import (
 	"github.com/google/uuid"
 )
 
+const createOAuthRefreshToken = `-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    NULL,
+    $4,
+    $5
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope
+`
+
+type CreateOAuthRefreshTokenParams struct {
+	Token     string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	ClientID  uuid.NullUUID
+	Scope     sql.NullString
+}
+
+func (q *Queries) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.ClientID,
+		arg.Scope,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scope,
+	)
+	return i, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
 VALUES (
@@ -23,7 +68,7 @@ VALUES (
     $3,
     NULL
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope
 `
 
 type CreateRefreshTokenParams struct {
@@ -42,12 +87,14 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scope,
 	)
 	return i, err
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope
 FROM refresh_tokens
 WHERE token = $1
 `
@@ -62,6 +109,8 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scope,
 	)
 	return i, err
 }
@@ -73,11 +122,14 @@ SELECT
     u.updated_at AS user_updated_at,
     u.email AS user_email,
     u.hashed_password AS user_hashed_password,
+    u.role AS user_role,
     r.token,
     r.created_at,
     r.updated_at,
     r.expires_at,
-    r.revoked_at
+    r.revoked_at,
+    r.client_id,
+    r.scope
 FROM refresh_tokens r
 JOIN users u ON u.id = r.user_id
 WHERE r.token = $1
@@ -89,11 +141,14 @@ type GetUserFromRefreshTokenRow struct {
 	UserUpdatedAt      time.Time
 	UserEmail          string
 	UserHashedPassword string
+	UserRole           string
 	Token              string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	ExpiresAt          time.Time
 	RevokedAt          sql.NullTime
+	ClientID           uuid.NullUUID
+	Scope              sql.NullString
 }
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
@@ -105,15 +160,33 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Ge
 		&i.UserUpdatedAt,
 		&i.UserEmail,
 		&i.UserHashedPassword,
+		&i.UserRole,
 		&i.Token,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		&i.Scope,
 	)
 	return i, err
 }
 
+const revokeActiveRefreshToken = `-- name: RevokeActiveRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeActiveRefreshToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeActiveRefreshToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy bounds how often work that failed with ErrSerialization is
+// tried again. The zero value tries once.
+type RetryPolicy struct {
+	// Attempts is the most times the work runs, including the first.
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles after each
+	// one, with jitter so that the transactions that collided spread out.
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy rides out the occasional deadlock between concurrent
+// requests without holding a request up for long.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 20 * time.Millisecond}
+
+// Do calls fn until it returns nil, an error other than ErrSerialization, or
+// the attempts or ctx run out. It returns fn's last error, classified.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := Classify(fn())
+		if err == nil || !errors.Is(err, ErrSerialization) || attempt >= p.Attempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func retry[T any](ctx context.Context, p RetryPolicy, fn func() (T, error)) (T, error) {
+	var v T
+	err := p.Do(ctx, func() error {
+		var err error
+		v, err = fn()
+		return err
+	})
+	return v, err
+}
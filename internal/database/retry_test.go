@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryPolicyDo(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001"}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", errs: []error{nil}, wantCalls: 1},
+		{name: "recovers", errs: []error{serialization, serialization, nil}, wantCalls: 3},
+		{name: "gives up", errs: []error{serialization, serialization, serialization, nil}, wantCalls: 3, wantErr: ErrSerialization},
+		{name: "not transient", errs: []error{sql.ErrNoRows, nil}, wantCalls: 1, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := RetryPolicy{Attempts: 3}.Do(context.Background(), func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("zero value tries once", func(t *testing.T) {
+		calls := 0
+		_ = RetryPolicy{}.Do(context.Background(), func() error {
+			calls++
+			return serialization
+		})
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		err := RetryPolicy{Attempts: 3, Backoff: time.Hour}.Do(ctx, func() error {
+			calls++
+			return serialization
+		})
+		if calls != 1 || !errors.Is(err, ErrSerialization) {
+			t.Errorf("Do() = %v after %d calls, want ErrSerialization after 1", err, calls)
+		}
+	})
+}
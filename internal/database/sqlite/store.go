@@ -0,0 +1,709 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Store adapts the SQLite queries to db.Querier, so handlers run unchanged
+// on either backend.
+//
+// The PostgreSQL queries call gen_random_uuid() and NOW(); here the store
+// supplies IDs and timestamps instead. Timestamps are written in UTC, which
+// keeps SQLite's text comparisons in chronological order.
+type Store struct {
+	q   *Queries
+	now func() time.Time
+}
+
+var _ db.Querier = (*Store)(nil)
+
+// NewStore returns a Store that runs queries on conn.
+func NewStore(conn DBTX) *Store {
+	return &Store{q: New(conn), now: time.Now}
+}
+
+// timestamp matches PostgreSQL's microsecond TIMESTAMP precision.
+func (s *Store) timestamp() time.Time {
+	return utc(s.now()).Truncate(time.Microsecond)
+}
+
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func nullUTC(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.UTC()
+	}
+	return t
+}
+
+// encodeStrings stores a PostgreSQL TEXT[] value as a JSON array.
+func encodeStrings(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func decodeStrings(column string) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal([]byte(column), &values); err != nil {
+		return nil, fmt.Errorf("decoding text array: %w", err)
+	}
+	return values, nil
+}
+
+func nullInt32(n sql.NullInt64) sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(n.Int64), Valid: n.Valid}
+}
+
+func loginThrottle(t LoginThrottle) db.LoginThrottle {
+	return db.LoginThrottle{
+		Key:         t.Key,
+		Failures:    int32(t.Failures),
+		LockedUntil: t.LockedUntil,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+func oauthClient(c OauthClient) (db.OauthClient, error) {
+	redirectURIs, err := decodeStrings(c.RedirectUris)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	scopes, err := decodeStrings(c.Scopes)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	return db.OauthClient{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		OwnerID:      c.OwnerID,
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		RedirectUris: redirectURIs,
+		Scopes:       scopes,
+	}, nil
+}
+
+func webhookDelivery(d WebhookDelivery) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:             d.ID,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       int32(d.Attempts),
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: nullInt32(d.LastStatusCode),
+		LastError:      d.LastError,
+	}
+}
+
+func webhookEndpoint(e WebhookEndpoint) (db.WebhookEndpoint, error) {
+	eventTypes, err := decodeStrings(e.EventTypes)
+	if err != nil {
+		return db.WebhookEndpoint{}, err
+	}
+	return db.WebhookEndpoint{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+		OwnerID:    e.OwnerID,
+		Url:        e.Url,
+		Secret:     e.Secret,
+		EventTypes: eventTypes,
+		AllUsers:   e.AllUsers,
+	}, nil
+}
+
+func webhookEvent(e WebhookEvent) db.WebhookEvent {
+	return db.WebhookEvent{
+		Source:      e.Source,
+		ID:          e.ID,
+		EventType:   e.EventType,
+		Payload:     e.Payload,
+		ReceivedAt:  e.ReceivedAt,
+		ProcessedAt: e.ProcessedAt,
+		Attempts:    int32(e.Attempts),
+		LastError:   e.LastError,
+	}
+}
+
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, arg db.ClaimDueWebhookDeliveriesParams) ([]db.ClaimDueWebhookDeliveriesRow, error) {
+	rows, err := s.q.ClaimDueWebhookDeliveries(ctx, ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    utc(arg.LeaseUntil),
+		Now:           s.timestamp(),
+		MaxDeliveries: int64(arg.MaxDeliveries),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []db.ClaimDueWebhookDeliveriesRow
+	for _, r := range rows {
+		out = append(out, db.ClaimDueWebhookDeliveriesRow{
+			ID:        r.ID,
+			EventID:   r.EventID,
+			EventType: r.EventType,
+			Payload:   r.Payload,
+			Attempts:  int32(r.Attempts),
+			Url:       r.Url,
+			Secret:    r.Secret,
+		})
+	}
+	return out, nil
+}
+
+func (s *Store) ClaimPublishedChirps(ctx context.Context, maxChirps int32) ([]uuid.UUID, error) {
+	return s.q.ClaimPublishedChirps(ctx, ClaimPublishedChirpsParams{
+		Now:       s.timestamp(),
+		MaxChirps: int64(maxChirps),
+	})
+}
+
+func (s *Store) ClaimWebhookEvent(ctx context.Context, arg db.ClaimWebhookEventParams) (int64, error) {
+	return s.q.ClaimWebhookEvent(ctx, ClaimWebhookEventParams{
+		Now:    s.timestamp(),
+		Source: arg.Source,
+		ID:     arg.ID,
+	})
+}
+
+func (s *Store) ClearLoginThrottle(ctx context.Context, key string) error {
+	return s.q.ClearLoginThrottle(ctx, key)
+}
+
+func (s *Store) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (db.OauthAuthorizationCode, error) {
+	code, err := s.q.ConsumeOAuthAuthorizationCode(ctx, codeHash)
+	return db.OauthAuthorizationCode(code), err
+}
+
+func (s *Store) ConsumeOIDCLoginState(ctx context.Context, state string) (db.OidcLoginState, error) {
+	st, err := s.q.ConsumeOIDCLoginState(ctx, state)
+	return db.OidcLoginState(st), err
+}
+
+func (s *Store) CountChirpPostsSince(ctx context.Context, arg db.CountChirpPostsSinceParams) (int64, error) {
+	return s.q.CountChirpPostsSince(ctx, CountChirpPostsSinceParams{
+		UserID:    arg.UserID,
+		CreatedAt: utc(arg.CreatedAt),
+	})
+}
+
+func (s *Store) CountUsersByRole(ctx context.Context, role string) (int64, error) {
+	return s.q.CountUsersByRole(ctx, role)
+}
+
+func (s *Store) CreateChirp(ctx context.Context, arg db.CreateChirpParams) (db.Chirp, error) {
+	chirp, err := s.q.CreateChirp(ctx, CreateChirpParams{
+		ID:          uuid.New(),
+		Now:         s.timestamp(),
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		PublishedAt: nullUTC(arg.PublishedAt),
+	})
+	return db.Chirp(chirp), err
+}
+
+func (s *Store) CreateChirpPost(ctx context.Context, userID uuid.UUID) error {
+	return s.q.CreateChirpPost(ctx, CreateChirpPostParams{
+		ID:     uuid.New(),
+		UserID: userID,
+		Now:    s.timestamp(),
+	})
+}
+
+func (s *Store) CreateLoginAttempt(ctx context.Context, arg db.CreateLoginAttemptParams) error {
+	return s.q.CreateLoginAttempt(ctx, CreateLoginAttemptParams{
+		ID:            uuid.New(),
+		Now:           s.timestamp(),
+		Email:         arg.Email,
+		UserID:        arg.UserID,
+		IpAddress:     arg.IpAddress,
+		UserAgent:     arg.UserAgent,
+		Succeeded:     arg.Succeeded,
+		FailureReason: arg.FailureReason,
+	})
+}
+
+func (s *Store) CreateOAuthAuthorizationCode(ctx context.Context, arg db.CreateOAuthAuthorizationCodeParams) error {
+	return s.q.CreateOAuthAuthorizationCode(ctx, CreateOAuthAuthorizationCodeParams{
+		CodeHash:      arg.CodeHash,
+		ClientID:      arg.ClientID,
+		UserID:        arg.UserID,
+		RedirectUri:   arg.RedirectUri,
+		Scope:         arg.Scope,
+		CodeChallenge: arg.CodeChallenge,
+		Now:           s.timestamp(),
+		ExpiresAt:     utc(arg.ExpiresAt),
+	})
+}
+
+func (s *Store) CreateOAuthClient(ctx context.Context, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+	redirectURIs, err := encodeStrings(arg.RedirectUris)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	scopes, err := encodeStrings(arg.Scopes)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	client, err := s.q.CreateOAuthClient(ctx, CreateOAuthClientParams{
+		ID:           uuid.New(),
+		Now:          s.timestamp(),
+		OwnerID:      arg.OwnerID,
+		Name:         arg.Name,
+		SecretHash:   arg.SecretHash,
+		RedirectUris: redirectURIs,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	return oauthClient(client)
+}
+
+func (s *Store) CreateOAuthRefreshToken(ctx context.Context, arg db.CreateOAuthRefreshTokenParams) (db.RefreshToken, error) {
+	token, err := s.q.CreateOAuthRefreshToken(ctx, CreateOAuthRefreshTokenParams{
+		Token:     arg.Token,
+		Now:       s.timestamp(),
+		UserID:    arg.UserID,
+		ExpiresAt: utc(arg.ExpiresAt),
+		ClientID:  arg.ClientID,
+		Scope:     arg.Scope,
+	})
+	return db.RefreshToken(token), err
+}
+
+func (s *Store) CreateOIDCLoginState(ctx context.Context, arg db.CreateOIDCLoginStateParams) error {
+	return s.q.CreateOIDCLoginState(ctx, CreateOIDCLoginStateParams{
+		State:        arg.State,
+		Provider:     arg.Provider,
+		Nonce:        arg.Nonce,
+		CodeVerifier: arg.CodeVerifier,
+		LinkUserID:   arg.LinkUserID,
+		UseCookies:   arg.UseCookies,
+		Now:          s.timestamp(),
+		ExpiresAt:    utc(arg.ExpiresAt),
+	})
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg db.CreateRefreshTokenParams) (db.RefreshToken, error) {
+	token, err := s.q.CreateRefreshToken(ctx, CreateRefreshTokenParams{
+		Token:     arg.Token,
+		Now:       s.timestamp(),
+		UserID:    arg.UserID,
+		ExpiresAt: utc(arg.ExpiresAt),
+	})
+	return db.RefreshToken(token), err
+}
+
+func (s *Store) CreateScheduledChirp(ctx context.Context, arg db.CreateScheduledChirpParams) error {
+	return s.q.CreateScheduledChirp(ctx, CreateScheduledChirpParams{
+		ChirpID:   arg.ChirpID,
+		PublishAt: utc(arg.PublishAt),
+	})
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	user, err := s.q.CreateUser(ctx, CreateUserParams{
+		ID:             uuid.New(),
+		Now:            s.timestamp(),
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	})
+	return db.User(user), err
+}
+
+func (s *Store) CreateUserIdentity(ctx context.Context, arg db.CreateUserIdentityParams) (db.UserIdentity, error) {
+	identity, err := s.q.CreateUserIdentity(ctx, CreateUserIdentityParams{
+		Provider: arg.Provider,
+		Subject:  arg.Subject,
+		UserID:   arg.UserID,
+		Email:    arg.Email,
+		Now:      s.timestamp(),
+	})
+	return db.UserIdentity(identity), err
+}
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, arg db.CreateWebhookEndpointParams) (db.WebhookEndpoint, error) {
+	eventTypes, err := encodeStrings(arg.EventTypes)
+	if err != nil {
+		return db.WebhookEndpoint{}, err
+	}
+	endpoint, err := s.q.CreateWebhookEndpoint(ctx, CreateWebhookEndpointParams{
+		ID:         uuid.New(),
+		Now:        s.timestamp(),
+		OwnerID:    arg.OwnerID,
+		Url:        arg.Url,
+		Secret:     arg.Secret,
+		EventTypes: eventTypes,
+		AllUsers:   arg.AllUsers,
+	})
+	if err != nil {
+		return db.WebhookEndpoint{}, err
+	}
+	return webhookEndpoint(endpoint)
+}
+
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteChirp(ctx, id)
+}
+
+func (s *Store) DeleteChirpPostsBefore(ctx context.Context, arg db.DeleteChirpPostsBeforeParams) error {
+	return s.q.DeleteChirpPostsBefore(ctx, DeleteChirpPostsBeforeParams{
+		UserID:    arg.UserID,
+		CreatedAt: utc(arg.CreatedAt),
+	})
+}
+
+func (s *Store) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) error {
+	return s.q.DeleteExpiredOAuthAuthorizationCodes(ctx, s.timestamp())
+}
+
+func (s *Store) DeleteExpiredOIDCLoginStates(ctx context.Context) error {
+	return s.q.DeleteExpiredOIDCLoginStates(ctx, s.timestamp())
+}
+
+func (s *Store) DeleteOAuthClient(ctx context.Context, arg db.DeleteOAuthClientParams) (int64, error) {
+	return s.q.DeleteOAuthClient(ctx, DeleteOAuthClientParams(arg))
+}
+
+func (s *Store) DeleteUserEntitlement(ctx context.Context, arg db.DeleteUserEntitlementParams) (int64, error) {
+	return s.q.DeleteUserEntitlement(ctx, DeleteUserEntitlementParams(arg))
+}
+
+func (s *Store) DeleteUsers(ctx context.Context) error {
+	return s.q.DeleteUsers(ctx)
+}
+
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, arg db.DeleteWebhookEndpointParams) (int64, error) {
+	return s.q.DeleteWebhookEndpoint(ctx, DeleteWebhookEndpointParams(arg))
+}
+
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, arg db.EnqueueWebhookDeliveriesParams) (int64, error) {
+	return s.q.EnqueueWebhookDeliveries(ctx, EnqueueWebhookDeliveriesParams{
+		Now:       s.timestamp(),
+		EventID:   arg.EventID,
+		EventType: arg.EventType,
+		Payload:   arg.Payload,
+		UserID:    arg.UserID,
+	})
+}
+
+func (s *Store) FailWebhookEvent(ctx context.Context, arg db.FailWebhookEventParams) error {
+	return s.q.FailWebhookEvent(ctx, FailWebhookEventParams{
+		LastError: arg.LastError,
+		Source:    arg.Source,
+		ID:        arg.ID,
+	})
+}
+
+func (s *Store) GetChirp(ctx context.Context, id uuid.UUID) (db.Chirp, error) {
+	chirp, err := s.q.GetChirp(ctx, id)
+	return db.Chirp(chirp), err
+}
+
+func (s *Store) ForgiveLoginFailure(ctx context.Context, key string) (db.LoginThrottle, error) {
+	throttle, err := s.q.ForgiveLoginFailure(ctx, key)
+	return loginThrottle(throttle), err
+}
+
+func (s *Store) GetLoginThrottle(ctx context.Context, key string) (db.LoginThrottle, error) {
+	throttle, err := s.q.GetLoginThrottle(ctx, key)
+	return loginThrottle(throttle), err
+}
+
+func (s *Store) GetOAuthClient(ctx context.Context, id uuid.UUID) (db.OauthClient, error) {
+	client, err := s.q.GetOAuthClient(ctx, id)
+	if err != nil {
+		return db.OauthClient{}, err
+	}
+	return oauthClient(client)
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, token string) (db.RefreshToken, error) {
+	t, err := s.q.GetRefreshToken(ctx, token)
+	return db.RefreshToken(t), err
+}
+
+func (s *Store) GetSubscription(ctx context.Context, userID uuid.UUID) (db.Subscription, error) {
+	sub, err := s.q.GetSubscription(ctx, userID)
+	return db.Subscription(sub), err
+}
+
+func (s *Store) GetUser(ctx context.Context, id uuid.UUID) (db.User, error) {
+	user, err := s.q.GetUser(ctx, id)
+	return db.User(user), err
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	user, err := s.q.GetUserByEmail(ctx, email)
+	return db.User(user), err
+}
+
+func (s *Store) GetUserFromRefreshToken(ctx context.Context, token string) (db.GetUserFromRefreshTokenRow, error) {
+	row, err := s.q.GetUserFromRefreshToken(ctx, token)
+	return db.GetUserFromRefreshTokenRow(row), err
+}
+
+func (s *Store) GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (db.UserIdentity, error) {
+	identity, err := s.q.GetUserIdentity(ctx, GetUserIdentityParams(arg))
+	return db.UserIdentity(identity), err
+}
+
+func (s *Store) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (db.WebhookEndpoint, error) {
+	endpoint, err := s.q.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return db.WebhookEndpoint{}, err
+	}
+	return webhookEndpoint(endpoint)
+}
+
+func (s *Store) GetWebhookEvent(ctx context.Context, arg db.GetWebhookEventParams) (db.WebhookEvent, error) {
+	event, err := s.q.GetWebhookEvent(ctx, GetWebhookEventParams(arg))
+	return webhookEvent(event), err
+}
+
+func (s *Store) ListChirps(ctx context.Context) ([]db.Chirp, error) {
+	chirps, err := s.q.ListChirps(ctx)
+	return convertChirps(chirps), err
+}
+
+func (s *Store) ListChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]db.Chirp, error) {
+	chirps, err := s.q.ListChirpsByAuthor(ctx, userID)
+	return convertChirps(chirps), err
+}
+
+func convertChirps(chirps []Chirp) []db.Chirp {
+	var out []db.Chirp
+	for _, c := range chirps {
+		out = append(out, db.Chirp(c))
+	}
+	return out
+}
+
+func (s *Store) ListOAuthClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]db.OauthClient, error) {
+	clients, err := s.q.ListOAuthClientsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var out []db.OauthClient
+	for _, c := range clients {
+		client, err := oauthClient(c)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, client)
+	}
+	return out, nil
+}
+
+func (s *Store) ListUserEntitlements(ctx context.Context, userID uuid.UUID) ([]db.UserEntitlement, error) {
+	entitlements, err := s.q.ListUserEntitlements(ctx, userID)
+	var out []db.UserEntitlement
+	for _, e := range entitlements {
+		out = append(out, db.UserEntitlement(e))
+	}
+	return out, err
+}
+
+func (s *Store) ListWebhookDeliveriesByEndpoint(ctx context.Context, arg db.ListWebhookDeliveriesByEndpointParams) ([]db.WebhookDelivery, error) {
+	deliveries, err := s.q.ListWebhookDeliveriesByEndpoint(ctx, ListWebhookDeliveriesByEndpointParams{
+		EndpointID: arg.EndpointID,
+		Limit:      int64(arg.Limit),
+	})
+	var out []db.WebhookDelivery
+	for _, d := range deliveries {
+		out = append(out, webhookDelivery(d))
+	}
+	return out, err
+}
+
+func (s *Store) ListWebhookEndpointsByOwner(ctx context.Context, ownerID uuid.UUID) ([]db.WebhookEndpoint, error) {
+	endpoints, err := s.q.ListWebhookEndpointsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var out []db.WebhookEndpoint
+	for _, e := range endpoints {
+		endpoint, err := webhookEndpoint(e)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, endpoint)
+	}
+	return out, nil
+}
+
+func (s *Store) ListWebhookEvents(ctx context.Context, limit int32) ([]db.WebhookEvent, error) {
+	events, err := s.q.ListWebhookEvents(ctx, int64(limit))
+	var out []db.WebhookEvent
+	for _, e := range events {
+		out = append(out, webhookEvent(e))
+	}
+	return out, err
+}
+
+func (s *Store) LockLoginThrottle(ctx context.Context, key string) (db.LoginThrottle, error) {
+	throttle, err := s.q.LockLoginThrottle(ctx, LockLoginThrottleParams{
+		Key: key,
+		Now: s.timestamp(),
+	})
+	return loginThrottle(throttle), err
+}
+
+func (s *Store) LockUser(ctx context.Context, id uuid.UUID) error {
+	return s.q.LockUser(ctx, id)
+}
+
+func (s *Store) MarkWebhookEventReplayed(ctx context.Context, arg db.MarkWebhookEventReplayedParams) error {
+	return s.q.MarkWebhookEventReplayed(ctx, MarkWebhookEventReplayedParams{
+		Now:    s.timestamp(),
+		Source: arg.Source,
+		ID:     arg.ID,
+	})
+}
+
+func (s *Store) RecordLoginFailure(ctx context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+	throttle, err := s.q.RecordLoginFailure(ctx, RecordLoginFailureParams{
+		Key:         arg.Key,
+		Now:         s.timestamp(),
+		WindowStart: utc(arg.WindowStart),
+	})
+	return loginThrottle(throttle), err
+}
+
+func (s *Store) RecordWebhookDeliveryAttempt(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) error {
+	return s.q.RecordWebhookDeliveryAttempt(ctx, RecordWebhookDeliveryAttemptParams{
+		Status:         arg.Status,
+		NextAttemptAt:  utc(arg.NextAttemptAt),
+		Now:            s.timestamp(),
+		LastStatusCode: sql.NullInt64{Int64: int64(arg.LastStatusCode.Int32), Valid: arg.LastStatusCode.Valid},
+		LastError:      arg.LastError,
+		ID:             arg.ID,
+	})
+}
+
+func (s *Store) RecordWebhookEvent(ctx context.Context, arg db.RecordWebhookEventParams) (db.WebhookEvent, error) {
+	event, err := s.q.RecordWebhookEvent(ctx, RecordWebhookEventParams{
+		Source:    arg.Source,
+		ID:        arg.ID,
+		EventType: arg.EventType,
+		Payload:   arg.Payload,
+		Now:       s.timestamp(),
+	})
+	return webhookEvent(event), err
+}
+
+func (s *Store) RecordWebhookEventError(ctx context.Context, arg db.RecordWebhookEventErrorParams) error {
+	return s.q.RecordWebhookEventError(ctx, RecordWebhookEventErrorParams{
+		LastError: arg.LastError,
+		Source:    arg.Source,
+		ID:        arg.ID,
+	})
+}
+
+func (s *Store) RedeliverWebhookDelivery(ctx context.Context, arg db.RedeliverWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	delivery, err := s.q.RedeliverWebhookDelivery(ctx, RedeliverWebhookDeliveryParams{
+		Now:     s.timestamp(),
+		ID:      arg.ID,
+		OwnerID: arg.OwnerID,
+	})
+	return webhookDelivery(delivery), err
+}
+
+func (s *Store) RevokeActiveRefreshToken(ctx context.Context, token string) (int64, error) {
+	return s.q.RevokeActiveRefreshToken(ctx, RevokeActiveRefreshTokenParams{
+		Now:   s.timestamp(),
+		Token: token,
+	})
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	return s.q.RevokeRefreshToken(ctx, RevokeRefreshTokenParams{
+		Now:   s.timestamp(),
+		Token: token,
+	})
+}
+
+func (s *Store) SetLoginLockout(ctx context.Context, arg db.SetLoginLockoutParams) error {
+	return s.q.SetLoginLockout(ctx, SetLoginLockoutParams{
+		LockedUntil: nullUTC(arg.LockedUntil),
+		Key:         arg.Key,
+	})
+}
+
+func (s *Store) SetUserRole(ctx context.Context, arg db.SetUserRoleParams) (db.User, error) {
+	user, err := s.q.SetUserRole(ctx, SetUserRoleParams{
+		Role: arg.Role,
+		Now:  s.timestamp(),
+		ID:   arg.ID,
+	})
+	return db.User(user), err
+}
+
+func (s *Store) UpdateChirpBody(ctx context.Context, arg db.UpdateChirpBodyParams) (db.Chirp, error) {
+	chirp, err := s.q.UpdateChirpBody(ctx, UpdateChirpBodyParams{
+		Body: arg.Body,
+		Now:  s.timestamp(),
+		ID:   arg.ID,
+	})
+	return db.Chirp(chirp), err
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	user, err := s.q.UpdateUser(ctx, UpdateUserParams{
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Now:            s.timestamp(),
+		ID:             arg.ID,
+	})
+	return db.User(user), err
+}
+
+func (s *Store) UpdateUserPasswordHash(ctx context.Context, arg db.UpdateUserPasswordHashParams) error {
+	return s.q.UpdateUserPasswordHash(ctx, UpdateUserPasswordHashParams{
+		HashedPassword: arg.HashedPassword,
+		ID:             arg.ID,
+	})
+}
+
+func (s *Store) UpsertSubscription(ctx context.Context, arg db.UpsertSubscriptionParams) (db.Subscription, error) {
+	sub, err := s.q.UpsertSubscription(ctx, UpsertSubscriptionParams{
+		UserID:             arg.UserID,
+		Status:             arg.Status,
+		CurrentPeriodStart: utc(arg.CurrentPeriodStart),
+		CurrentPeriodEnd:   utc(arg.CurrentPeriodEnd),
+		Now:                s.timestamp(),
+	})
+	return db.Subscription(sub), err
+}
+
+func (s *Store) UpsertUserEntitlement(ctx context.Context, arg db.UpsertUserEntitlementParams) (db.UserEntitlement, error) {
+	entitlement, err := s.q.UpsertUserEntitlement(ctx, UpsertUserEntitlementParams{
+		UserID:    arg.UserID,
+		Feature:   arg.Feature,
+		Granted:   arg.Granted,
+		GrantedBy: arg.GrantedBy,
+		Now:       s.timestamp(),
+	})
+	return db.UserEntitlement(entitlement), err
+}
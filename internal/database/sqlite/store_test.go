@@ -0,0 +1,232 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	db "chirpy/internal/database"
+	"chirpy/internal/migrate"
+	"chirpy/internal/storage"
+	"github.com/google/uuid"
+)
+
+func newStore(t *testing.T) db.Querier {
+	t.Helper()
+	conn, err := storage.Open("sqlite::memory:", storage.PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	provider, err := migrate.NewProvider(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return conn.Queries(conn)
+}
+
+func TestUsersAndChirps(t *testing.T) {
+	ctx := context.Background()
+	q := newStore(t)
+
+	user, err := q.CreateUser(ctx, db.CreateUserParams{Email: "alice@example.com", HashedPassword: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "user" || user.CreatedAt.Location() != time.UTC {
+		t.Errorf("CreateUser() = %+v", user)
+	}
+
+	_, err = q.CreateUser(ctx, db.CreateUserParams{Email: "alice@example.com", HashedPassword: "hash"})
+	if !db.IsConflict(err, "users_email_key") {
+		t.Errorf("duplicate email: error = %v, want a conflict on users_email_key", err)
+	}
+	_, err = q.CreateChirp(ctx, db.CreateChirpParams{Body: "hi", UserID: uuid.New()})
+	if !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("chirp by unknown user: error = %v, want db.ErrNotFound", err)
+	}
+
+	chirp, err := q.CreateChirp(ctx, db.CreateChirpParams{Body: "hello", UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !chirp.PublishedAt.Equal(chirp.CreatedAt) {
+		t.Errorf("PublishedAt = %v, want CreatedAt %v", chirp.PublishedAt, chirp.CreatedAt)
+	}
+	if err := q.CreateChirpPost(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	n, err := q.CountChirpPostsSince(ctx, db.CountChirpPostsSinceParams{
+		UserID:    user.ID,
+		CreatedAt: chirp.CreatedAt.Add(-time.Second).In(time.FixedZone("EST", -5*60*60)),
+	})
+	if err != nil || n != 1 {
+		t.Errorf("CountChirpPostsSince() = %d, %v, want 1", n, err)
+	}
+
+	if err := q.CreateScheduledChirp(ctx, db.CreateScheduledChirpParams{ChirpID: chirp.ID, PublishAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range [][]uuid.UUID{{chirp.ID}, nil} {
+		if ids, err := q.ClaimPublishedChirps(ctx, 10); err != nil || !slices.Equal(ids, want) {
+			t.Errorf("ClaimPublishedChirps() = %v, %v, want %v", ids, err, want)
+		}
+	}
+
+	got, err := q.GetChirp(ctx, chirp.ID)
+	if err != nil || got != chirp {
+		t.Errorf("GetChirp() = %+v, %v, want %+v", got, err, chirp)
+	}
+
+	token, err := q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{Token: "t1", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{Token: "t1", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	if !db.IsConflict(err, "refresh_tokens_pkey") {
+		t.Errorf("duplicate refresh token: error = %v, want a conflict on refresh_tokens_pkey", err)
+	}
+	row, err := q.GetUserFromRefreshToken(ctx, token.Token)
+	if err != nil || row.UserEmail != user.Email {
+		t.Errorf("GetUserFromRefreshToken() = %+v, %v", row, err)
+	}
+	if n, err := q.RevokeActiveRefreshToken(ctx, token.Token); err != nil || n != 1 {
+		t.Errorf("RevokeActiveRefreshToken() = %d, %v, want 1", n, err)
+	}
+	if n, _ := q.RevokeActiveRefreshToken(ctx, token.Token); n != 0 {
+		t.Errorf("revoking twice affected %d rows, want 0", n)
+	}
+
+	if err := q.DeleteUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.GetChirp(ctx, chirp.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("chirp survived deleting its author: %v", err)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	q := newStore(t)
+
+	if throttle, err := q.LockLoginThrottle(ctx, "ip:1"); err != nil || throttle.Failures != 0 {
+		t.Fatalf("LockLoginThrottle() = %+v, %v, want a new throttle", throttle, err)
+	}
+
+	windowStart := time.Now().Add(-time.Minute)
+	for want := int32(1); want <= 3; want++ {
+		throttle, err := q.RecordLoginFailure(ctx, db.RecordLoginFailureParams{Key: "ip:1", WindowStart: windowStart})
+		if err != nil || throttle.Failures != want {
+			t.Fatalf("RecordLoginFailure() = %d, %v, want %d", throttle.Failures, err, want)
+		}
+	}
+
+	// Failures recorded before the window are forgotten.
+	throttle, err := q.RecordLoginFailure(ctx, db.RecordLoginFailureParams{Key: "ip:1", WindowStart: time.Now().Add(time.Minute)})
+	if err != nil || throttle.Failures != 1 {
+		t.Errorf("RecordLoginFailure() after window = %d, %v, want 1", throttle.Failures, err)
+	}
+	if throttle, err := q.LockLoginThrottle(ctx, "ip:1"); err != nil || throttle.Failures != 1 {
+		t.Errorf("LockLoginThrottle() = %+v, %v, want the existing throttle", throttle, err)
+	}
+
+	if throttle, err := q.ForgiveLoginFailure(ctx, "ip:1"); err != nil || throttle.Failures != 0 {
+		t.Errorf("ForgiveLoginFailure() = %+v, %v, want no failures left", throttle, err)
+	}
+	if _, err := q.ForgiveLoginFailure(ctx, "ip:1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ForgiveLoginFailure() with no failures = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	q := newStore(t)
+
+	owner, err := q.CreateUser(ctx, db.CreateUserParams{Email: "owner@example.com", HashedPassword: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := q.CreateWebhookEndpoint(ctx, db.CreateWebhookEndpointParams{
+		OwnerID:    owner.ID,
+		Url:        "https://example.com/hook",
+		Secret:     "secret",
+		EventTypes: []string{"chirp.created", "user.upgraded"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(endpoint.EventTypes, []string{"chirp.created", "user.upgraded"}) {
+		t.Errorf("EventTypes = %q", endpoint.EventTypes)
+	}
+
+	payload := json.RawMessage(`{"id":1}`)
+	for eventType, want := range map[string]int64{"chirp.created": 1, "chirp.deleted": 0} {
+		n, err := q.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+			EventID:   uuid.New(),
+			EventType: eventType,
+			Payload:   payload,
+			UserID:    owner.ID,
+		})
+		if err != nil || n != want {
+			t.Errorf("EnqueueWebhookDeliveries(%s) = %d, %v, want %d", eventType, n, err, want)
+		}
+	}
+
+	claimed, err := q.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    time.Now().Add(time.Minute),
+		MaxDeliveries: 10,
+	})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDueWebhookDeliveries() = %+v, %v, want one delivery", claimed, err)
+	}
+	if claimed[0].Url != endpoint.Url || string(claimed[0].Payload) != string(payload) {
+		t.Errorf("claimed delivery = %+v", claimed[0])
+	}
+	again, err := q.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    time.Now().Add(time.Minute),
+		MaxDeliveries: 10,
+	})
+	if err != nil || len(again) != 0 {
+		t.Errorf("leased delivery claimed again: %+v, %v", again, err)
+	}
+
+	err = q.RecordWebhookDeliveryAttempt(ctx, db.RecordWebhookDeliveryAttemptParams{
+		ID:             claimed[0].ID,
+		Status:         "bogus",
+		NextAttemptAt:  time.Now(),
+		LastStatusCode: sql.NullInt32{Int32: 500, Valid: true},
+	})
+	if err == nil {
+		t.Error("invalid delivery status accepted")
+	}
+
+	if _, err := q.RedeliverWebhookDelivery(ctx, db.RedeliverWebhookDeliveryParams{ID: claimed[0].ID, OwnerID: uuid.New()}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("redelivering another owner's delivery: error = %v, want sql.ErrNoRows", err)
+	}
+	delivery, err := q.RedeliverWebhookDelivery(ctx, db.RedeliverWebhookDeliveryParams{ID: claimed[0].ID, OwnerID: owner.ID})
+	if err != nil || delivery.Status != "pending" || delivery.EndpointID != endpoint.ID {
+		t.Errorf("RedeliverWebhookDelivery() = %+v, %v", delivery, err)
+	}
+
+	event, err := q.RecordWebhookEvent(ctx, db.RecordWebhookEventParams{Source: "polka", ID: "evt_1", EventType: "user.upgraded", Payload: payload})
+	if err != nil || event.Attempts != 1 {
+		t.Fatalf("RecordWebhookEvent() = %+v, %v", event, err)
+	}
+	if event, _ = q.RecordWebhookEvent(ctx, db.RecordWebhookEventParams{Source: "polka", ID: "evt_1", EventType: "user.upgraded", Payload: payload}); event.Attempts != 2 {
+		t.Errorf("redelivered event Attempts = %d, want 2", event.Attempts)
+	}
+	if n, err := q.ClaimWebhookEvent(ctx, db.ClaimWebhookEventParams{Source: "polka", ID: "evt_1"}); err != nil || n != 1 {
+		t.Errorf("ClaimWebhookEvent() = %d, %v, want 1", n, err)
+	}
+	if n, _ := q.ClaimWebhookEvent(ctx, db.ClaimWebhookEventParams{Source: "polka", ID: "evt_1"}); n != 0 {
+		t.Errorf("claiming a processed event affected %d rows, want 0", n)
+	}
+}
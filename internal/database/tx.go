@@ -0,0 +1,10 @@
+package database
+
+import "context"
+
+// Transactor runs work that must commit or fail as a whole. fn receives
+// queries bound to the transaction, and everything it does is rolled back
+// if it returns an error.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(Querier) error) error
+}
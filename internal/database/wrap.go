@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Wrap returns a Querier that classifies the errors q returns (see Classify)
+// and retries statements that fail with ErrSerialization under retry. Use
+// the zero RetryPolicy for a q bound to a transaction: a failed statement
+// aborts the transaction, so only the whole transaction can be retried.
+func Wrap(q Querier, retry RetryPolicy) Querier {
+	return &classified{q: q, retry: retry}
+}
+
+type classified struct {
+	q     Querier
+	retry RetryPolicy
+}
+
+func (c *classified) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]ClaimDueWebhookDeliveriesRow, error) {
+	return retry(ctx, c.retry, func() ([]ClaimDueWebhookDeliveriesRow, error) { return c.q.ClaimDueWebhookDeliveries(ctx, arg) })
+}
+
+func (c *classified) ClaimPublishedChirps(ctx context.Context, maxChirps int32) ([]uuid.UUID, error) {
+	return retry(ctx, c.retry, func() ([]uuid.UUID, error) { return c.q.ClaimPublishedChirps(ctx, maxChirps) })
+}
+
+func (c *classified) ClaimWebhookEvent(ctx context.Context, arg ClaimWebhookEventParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.ClaimWebhookEvent(ctx, arg) })
+}
+
+func (c *classified) ClearLoginThrottle(ctx context.Context, key string) error {
+	return c.retry.Do(ctx, func() error { return c.q.ClearLoginThrottle(ctx, key) })
+}
+
+func (c *classified) ConsumeOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error) {
+	return retry(ctx, c.retry, func() (OauthAuthorizationCode, error) { return c.q.ConsumeOAuthAuthorizationCode(ctx, codeHash) })
+}
+
+func (c *classified) ConsumeOIDCLoginState(ctx context.Context, state string) (OidcLoginState, error) {
+	return retry(ctx, c.retry, func() (OidcLoginState, error) { return c.q.ConsumeOIDCLoginState(ctx, state) })
+}
+
+func (c *classified) CountChirpPostsSince(ctx context.Context, arg CountChirpPostsSinceParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.CountChirpPostsSince(ctx, arg) })
+}
+
+func (c *classified) CountUsersByRole(ctx context.Context, role string) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.CountUsersByRole(ctx, role) })
+}
+
+func (c *classified) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
+	return retry(ctx, c.retry, func() (Chirp, error) { return c.q.CreateChirp(ctx, arg) })
+}
+
+func (c *classified) CreateChirpPost(ctx context.Context, userID uuid.UUID) error {
+	return c.retry.Do(ctx, func() error { return c.q.CreateChirpPost(ctx, userID) })
+}
+
+func (c *classified) CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.CreateLoginAttempt(ctx, arg) })
+}
+
+func (c *classified) CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.CreateOAuthAuthorizationCode(ctx, arg) })
+}
+
+func (c *classified) CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error) {
+	return retry(ctx, c.retry, func() (OauthClient, error) { return c.q.CreateOAuthClient(ctx, arg) })
+}
+
+func (c *classified) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (RefreshToken, error) {
+	return retry(ctx, c.retry, func() (RefreshToken, error) { return c.q.CreateOAuthRefreshToken(ctx, arg) })
+}
+
+func (c *classified) CreateOIDCLoginState(ctx context.Context, arg CreateOIDCLoginStateParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.CreateOIDCLoginState(ctx, arg) })
+}
+
+func (c *classified) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
+	return retry(ctx, c.retry, func() (RefreshToken, error) { return c.q.CreateRefreshToken(ctx, arg) })
+}
+
+func (c *classified) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.CreateScheduledChirp(ctx, arg) })
+}
+
+func (c *classified) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	return retry(ctx, c.retry, func() (User, error) { return c.q.CreateUser(ctx, arg) })
+}
+
+func (c *classified) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error) {
+	return retry(ctx, c.retry, func() (UserIdentity, error) { return c.q.CreateUserIdentity(ctx, arg) })
+}
+
+func (c *classified) CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error) {
+	return retry(ctx, c.retry, func() (WebhookEndpoint, error) { return c.q.CreateWebhookEndpoint(ctx, arg) })
+}
+
+func (c *classified) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return c.retry.Do(ctx, func() error { return c.q.DeleteChirp(ctx, id) })
+}
+
+func (c *classified) DeleteChirpPostsBefore(ctx context.Context, arg DeleteChirpPostsBeforeParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.DeleteChirpPostsBefore(ctx, arg) })
+}
+
+func (c *classified) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context) error {
+	return c.retry.Do(ctx, func() error { return c.q.DeleteExpiredOAuthAuthorizationCodes(ctx) })
+}
+
+func (c *classified) DeleteExpiredOIDCLoginStates(ctx context.Context) error {
+	return c.retry.Do(ctx, func() error { return c.q.DeleteExpiredOIDCLoginStates(ctx) })
+}
+
+func (c *classified) DeleteOAuthClient(ctx context.Context, arg DeleteOAuthClientParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.DeleteOAuthClient(ctx, arg) })
+}
+
+func (c *classified) DeleteUserEntitlement(ctx context.Context, arg DeleteUserEntitlementParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.DeleteUserEntitlement(ctx, arg) })
+}
+
+func (c *classified) DeleteUsers(ctx context.Context) error {
+	return c.retry.Do(ctx, func() error { return c.q.DeleteUsers(ctx) })
+}
+
+func (c *classified) DeleteWebhookEndpoint(ctx context.Context, arg DeleteWebhookEndpointParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.DeleteWebhookEndpoint(ctx, arg) })
+}
+
+func (c *classified) EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.EnqueueWebhookDeliveries(ctx, arg) })
+}
+
+func (c *classified) FailWebhookEvent(ctx context.Context, arg FailWebhookEventParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.FailWebhookEvent(ctx, arg) })
+}
+
+func (c *classified) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	return retry(ctx, c.retry, func() (Chirp, error) { return c.q.GetChirp(ctx, id) })
+}
+
+func (c *classified) ForgiveLoginFailure(ctx context.Context, key string) (LoginThrottle, error) {
+	return retry(ctx, c.retry, func() (LoginThrottle, error) { return c.q.ForgiveLoginFailure(ctx, key) })
+}
+
+func (c *classified) GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error) {
+	return retry(ctx, c.retry, func() (LoginThrottle, error) { return c.q.GetLoginThrottle(ctx, key) })
+}
+
+func (c *classified) GetOAuthClient(ctx context.Context, id uuid.UUID) (OauthClient, error) {
+	return retry(ctx, c.retry, func() (OauthClient, error) { return c.q.GetOAuthClient(ctx, id) })
+}
+
+func (c *classified) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	return retry(ctx, c.retry, func() (RefreshToken, error) { return c.q.GetRefreshToken(ctx, token) })
+}
+
+func (c *classified) GetSubscription(ctx context.Context, userID uuid.UUID) (Subscription, error) {
+	return retry(ctx, c.retry, func() (Subscription, error) { return c.q.GetSubscription(ctx, userID) })
+}
+
+func (c *classified) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	return retry(ctx, c.retry, func() (User, error) { return c.q.GetUser(ctx, id) })
+}
+
+func (c *classified) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return retry(ctx, c.retry, func() (User, error) { return c.q.GetUserByEmail(ctx, email) })
+}
+
+func (c *classified) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
+	return retry(ctx, c.retry, func() (GetUserFromRefreshTokenRow, error) { return c.q.GetUserFromRefreshToken(ctx, token) })
+}
+
+func (c *classified) GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error) {
+	return retry(ctx, c.retry, func() (UserIdentity, error) { return c.q.GetUserIdentity(ctx, arg) })
+}
+
+func (c *classified) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error) {
+	return retry(ctx, c.retry, func() (WebhookEndpoint, error) { return c.q.GetWebhookEndpoint(ctx, id) })
+}
+
+func (c *classified) GetWebhookEvent(ctx context.Context, arg GetWebhookEventParams) (WebhookEvent, error) {
+	return retry(ctx, c.retry, func() (WebhookEvent, error) { return c.q.GetWebhookEvent(ctx, arg) })
+}
+
+func (c *classified) ListChirps(ctx context.Context) ([]Chirp, error) {
+	return retry(ctx, c.retry, func() ([]Chirp, error) { return c.q.ListChirps(ctx) })
+}
+
+func (c *classified) ListChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	return retry(ctx, c.retry, func() ([]Chirp, error) { return c.q.ListChirpsByAuthor(ctx, userID) })
+}
+
+func (c *classified) ListOAuthClientsByOwner(ctx context.Context, ownerID uuid.UUID) ([]OauthClient, error) {
+	return retry(ctx, c.retry, func() ([]OauthClient, error) { return c.q.ListOAuthClientsByOwner(ctx, ownerID) })
+}
+
+func (c *classified) ListUserEntitlements(ctx context.Context, userID uuid.UUID) ([]UserEntitlement, error) {
+	return retry(ctx, c.retry, func() ([]UserEntitlement, error) { return c.q.ListUserEntitlements(ctx, userID) })
+}
+
+func (c *classified) ListWebhookDeliveriesByEndpoint(ctx context.Context, arg ListWebhookDeliveriesByEndpointParams) ([]WebhookDelivery, error) {
+	return retry(ctx, c.retry, func() ([]WebhookDelivery, error) { return c.q.ListWebhookDeliveriesByEndpoint(ctx, arg) })
+}
+
+func (c *classified) ListWebhookEndpointsByOwner(ctx context.Context, ownerID uuid.UUID) ([]WebhookEndpoint, error) {
+	return retry(ctx, c.retry, func() ([]WebhookEndpoint, error) { return c.q.ListWebhookEndpointsByOwner(ctx, ownerID) })
+}
+
+func (c *classified) ListWebhookEvents(ctx context.Context, limit int32) ([]WebhookEvent, error) {
+	return retry(ctx, c.retry, func() ([]WebhookEvent, error) { return c.q.ListWebhookEvents(ctx, limit) })
+}
+
+func (c *classified) LockLoginThrottle(ctx context.Context, key string) (LoginThrottle, error) {
+	return retry(ctx, c.retry, func() (LoginThrottle, error) { return c.q.LockLoginThrottle(ctx, key) })
+}
+
+func (c *classified) LockUser(ctx context.Context, id uuid.UUID) error {
+	return c.retry.Do(ctx, func() error { return c.q.LockUser(ctx, id) })
+}
+
+func (c *classified) MarkWebhookEventReplayed(ctx context.Context, arg MarkWebhookEventReplayedParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.MarkWebhookEventReplayed(ctx, arg) })
+}
+
+func (c *classified) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	return retry(ctx, c.retry, func() (LoginThrottle, error) { return c.q.RecordLoginFailure(ctx, arg) })
+}
+
+func (c *classified) RecordWebhookDeliveryAttempt(ctx context.Context, arg RecordWebhookDeliveryAttemptParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.RecordWebhookDeliveryAttempt(ctx, arg) })
+}
+
+func (c *classified) RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (WebhookEvent, error) {
+	return retry(ctx, c.retry, func() (WebhookEvent, error) { return c.q.RecordWebhookEvent(ctx, arg) })
+}
+
+func (c *classified) RecordWebhookEventError(ctx context.Context, arg RecordWebhookEventErrorParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.RecordWebhookEventError(ctx, arg) })
+}
+
+func (c *classified) RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (WebhookDelivery, error) {
+	return retry(ctx, c.retry, func() (WebhookDelivery, error) { return c.q.RedeliverWebhookDelivery(ctx, arg) })
+}
+
+func (c *classified) RevokeActiveRefreshToken(ctx context.Context, token string) (int64, error) {
+	return retry(ctx, c.retry, func() (int64, error) { return c.q.RevokeActiveRefreshToken(ctx, token) })
+}
+
+func (c *classified) RevokeRefreshToken(ctx context.Context, token string) error {
+	return c.retry.Do(ctx, func() error { return c.q.RevokeRefreshToken(ctx, token) })
+}
+
+func (c *classified) SetLoginLockout(ctx context.Context, arg SetLoginLockoutParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.SetLoginLockout(ctx, arg) })
+}
+
+func (c *classified) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	return retry(ctx, c.retry, func() (User, error) { return c.q.SetUserRole(ctx, arg) })
+}
+
+func (c *classified) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	return retry(ctx, c.retry, func() (Chirp, error) { return c.q.UpdateChirpBody(ctx, arg) })
+}
+
+func (c *classified) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	return retry(ctx, c.retry, func() (User, error) { return c.q.UpdateUser(ctx, arg) })
+}
+
+func (c *classified) UpdateUserPasswordHash(ctx context.Context, arg UpdateUserPasswordHashParams) error {
+	return c.retry.Do(ctx, func() error { return c.q.UpdateUserPasswordHash(ctx, arg) })
+}
+
+func (c *classified) UpsertSubscription(ctx context.Context, arg UpsertSubscriptionParams) (Subscription, error) {
+	return retry(ctx, c.retry, func() (Subscription, error) { return c.q.UpsertSubscription(ctx, arg) })
+}
+
+func (c *classified) UpsertUserEntitlement(ctx context.Context, arg UpsertUserEntitlementParams) (UserEntitlement, error) {
+	return retry(ctx, c.retry, func() (UserEntitlement, error) { return c.q.UpsertUserEntitlement(ctx, arg) })
+}
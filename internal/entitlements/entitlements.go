@@ -0,0 +1,138 @@
+// Package entitlements decides which paid features a Chirpy account may use.
+//
+// A user's plan supplies a default set of features; administrators can then
+// grant extra features or revoke included ones per user. Handlers ask for a
+// feature or a limit rather than checking the plan directly.
+package entitlements
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Feature is a capability that can be granted to an account.
+type Feature string
+
+const (
+	// FeatureLongChirps raises the chirp length limit.
+	FeatureLongChirps Feature = "long_chirps"
+	// FeatureEditChirps allows authors to edit chirps after posting.
+	FeatureEditChirps Feature = "edit_chirps"
+	// FeatureScheduledChirps allows chirps to be published in the future.
+	FeatureScheduledChirps Feature = "scheduled_chirps"
+	// FeatureHigherRateLimit raises the chirp posting rate limit.
+	FeatureHigherRateLimit Feature = "higher_rate_limit"
+)
+
+// Features lists every known feature with a short description.
+var Features = map[Feature]string{
+	FeatureLongChirps:      "Post chirps longer than 140 characters",
+	FeatureEditChirps:      "Edit chirps after posting",
+	FeatureScheduledChirps: "Schedule chirps to publish later",
+	FeatureHigherRateLimit: "Post more chirps per hour",
+}
+
+// ParseFeature validates a feature name.
+func ParseFeature(s string) (Feature, error) {
+	f := Feature(s)
+	if _, ok := Features[f]; !ok {
+		return "", fmt.Errorf("unknown feature %q", s)
+	}
+	return f, nil
+}
+
+// Plan is the billing plan an account is on.
+type Plan string
+
+const (
+	PlanFree Plan = "free"
+	PlanRed  Plan = "chirpy_red"
+)
+
+var planFeatures = map[Plan][]Feature{
+	PlanFree: nil,
+	PlanRed: {
+		FeatureLongChirps,
+		FeatureEditChirps,
+		FeatureScheduledChirps,
+		FeatureHigherRateLimit,
+	},
+}
+
+// Limits applied to chirp posting.
+const (
+	StandardChirpLength = 140
+	LongChirpLength     = 1000
+
+	StandardChirpsPerHour = 30
+	HigherChirpsPerHour   = 300
+)
+
+// ChirpRateWindow is the window the chirps-per-hour limits are counted over.
+const ChirpRateWindow = time.Hour
+
+// Override grants or revokes a single feature for one account regardless of
+// its plan.
+type Override struct {
+	Feature Feature
+	Granted bool
+}
+
+// Set is the resolved list of features an account may use.
+type Set struct {
+	Plan     Plan
+	features map[Feature]bool
+}
+
+// Resolve combines the features included in plan with per-account overrides.
+// Overrides for unknown features are ignored.
+func Resolve(plan Plan, overrides []Override) Set {
+	s := Set{Plan: plan, features: make(map[Feature]bool)}
+	for _, f := range planFeatures[plan] {
+		s.features[f] = true
+	}
+	for _, o := range overrides {
+		if _, ok := Features[o.Feature]; !ok {
+			continue
+		}
+		if o.Granted {
+			s.features[o.Feature] = true
+		} else {
+			delete(s.features, o.Feature)
+		}
+	}
+	return s
+}
+
+// Has reports whether the set includes f.
+func (s Set) Has(f Feature) bool {
+	return s.features[f]
+}
+
+// List returns the features in the set in sorted order.
+func (s Set) List() []Feature {
+	list := make([]Feature, 0, len(s.features))
+	for f := range s.features {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// ChirpLength is the maximum chirp length, in bytes, the account may post.
+func (s Set) ChirpLength() int {
+	if s.Has(FeatureLongChirps) {
+		return LongChirpLength
+	}
+	return StandardChirpLength
+}
+
+// ChirpsPerHour is the number of chirps the account may post per
+// ChirpRateWindow.
+func (s Set) ChirpsPerHour() int {
+	if s.Has(FeatureHigherRateLimit) {
+		return HigherChirpsPerHour
+	}
+	return StandardChirpsPerHour
+}
@@ -0,0 +1,69 @@
+package entitlements
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      Plan
+		overrides []Override
+		want      []Feature
+	}{
+		{name: "free plan", plan: PlanFree, want: []Feature{}},
+		{
+			name: "red plan",
+			plan: PlanRed,
+			want: []Feature{FeatureEditChirps, FeatureHigherRateLimit, FeatureLongChirps, FeatureScheduledChirps},
+		},
+		{
+			name:      "grant on free plan",
+			plan:      PlanFree,
+			overrides: []Override{{Feature: FeatureEditChirps, Granted: true}},
+			want:      []Feature{FeatureEditChirps},
+		},
+		{
+			name:      "revoke from red plan",
+			plan:      PlanRed,
+			overrides: []Override{{Feature: FeatureLongChirps}, {Feature: FeatureScheduledChirps}},
+			want:      []Feature{FeatureEditChirps, FeatureHigherRateLimit},
+		},
+		{
+			name:      "unknown override ignored",
+			plan:      PlanFree,
+			overrides: []Override{{Feature: "teleport", Granted: true}},
+			want:      []Feature{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.plan, tt.overrides).List(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Resolve().List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	free := Resolve(PlanFree, nil)
+	if free.ChirpLength() != StandardChirpLength || free.ChirpsPerHour() != StandardChirpsPerHour {
+		t.Errorf("free limits = %d chars, %d/h", free.ChirpLength(), free.ChirpsPerHour())
+	}
+
+	red := Resolve(PlanRed, nil)
+	if red.ChirpLength() != LongChirpLength || red.ChirpsPerHour() != HigherChirpsPerHour {
+		t.Errorf("red limits = %d chars, %d/h", red.ChirpLength(), red.ChirpsPerHour())
+	}
+}
+
+func TestParseFeature(t *testing.T) {
+	if f, err := ParseFeature("edit_chirps"); err != nil || f != FeatureEditChirps {
+		t.Fatalf("ParseFeature(edit_chirps) = %q, %v", f, err)
+	}
+	if _, err := ParseFeature("teleport"); err == nil {
+		t.Fatalf("ParseFeature(teleport) expected error")
+	}
+}
@@ -0,0 +1,152 @@
+// Package health runs the readiness checks behind /api/readyz and reports
+// them as JSON.
+//
+// Each check gets its own deadline, and they run concurrently, so one hung
+// dependency cannot hold a probe past its timeout.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the outcome of a check, or of the report as a whole.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+	// StatusDraining reports a server that is shutting down. Its checks may
+	// still pass, but it should receive no new traffic.
+	StatusDraining Status = "draining"
+)
+
+// Check inspects one dependency. A non-nil error fails the check; details,
+// such as pool statistics, are reported either way.
+type Check func(ctx context.Context) (details any, err error)
+
+// Result is the outcome of one check.
+type Result struct {
+	Status  Status `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Report is the outcome of every check. Status is ok only when every check
+// passed and the server is not draining.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker holds the readiness checks for one server. A nil *Checker has no
+// checks and is always ready.
+type Checker struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker returns a Checker that fails any check still running after
+// timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers check under name. Add is not safe to call once the Checker
+// is serving.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the server as shutting down, so it reports not ready from now
+// on.
+func (c *Checker) Drain() {
+	if c != nil {
+		c.draining.Store(true)
+	}
+}
+
+// Run runs every check and reports the results.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: map[string]Result{}}
+	if c == nil {
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Go(func() {
+			result := run(ctx, nc.check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		})
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// run calls check, giving up when ctx expires even if check ignores it.
+func run(ctx context.Context, check Check) Result {
+	type outcome struct {
+		details any
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		details, err := check(ctx)
+		done <- outcome{details, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return Result{Status: StatusFail, Error: o.err.Error(), Details: o.details}
+		}
+		return Result{Status: StatusOK, Details: o.details}
+	case <-ctx.Done():
+		return Result{Status: StatusFail, Error: "timed out"}
+	}
+}
+
+// ServeHTTP runs the checks and writes the report, with status 200 when the
+// server is ready and 503 otherwise.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, "could not encode report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
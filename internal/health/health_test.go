@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	ok := func(ctx context.Context) (any, error) { return map[string]int{"conns": 1}, nil }
+	failing := func(ctx context.Context) (any, error) { return nil, errors.New("connection refused") }
+	hung := func(ctx context.Context) (any, error) { select {} }
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		drain      bool
+		wantStatus Status
+		wantCode   int
+		want       map[string]Status
+	}{
+		{name: "no checks", wantStatus: StatusOK, wantCode: http.StatusOK, want: map[string]Status{}},
+		{name: "all pass", checks: map[string]Check{"database": ok, "queue": ok}, wantStatus: StatusOK, wantCode: http.StatusOK, want: map[string]Status{"database": StatusOK, "queue": StatusOK}},
+		{name: "one fails", checks: map[string]Check{"database": failing, "queue": ok}, wantStatus: StatusFail, wantCode: http.StatusServiceUnavailable, want: map[string]Status{"database": StatusFail, "queue": StatusOK}},
+		{name: "one hangs", checks: map[string]Check{"database": hung, "queue": ok}, wantStatus: StatusFail, wantCode: http.StatusServiceUnavailable, want: map[string]Status{"database": StatusFail, "queue": StatusOK}},
+		{name: "draining", checks: map[string]Check{"database": ok}, drain: true, wantStatus: StatusDraining, wantCode: http.StatusServiceUnavailable, want: map[string]Status{"database": StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				c.Add(name, check)
+			}
+			if tt.drain {
+				c.Drain()
+			}
+
+			rec := httptest.NewRecorder()
+			c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.wantStatus || len(report.Checks) != len(tt.want) {
+				t.Fatalf("report = %+v, want status %s with %d checks", report, tt.wantStatus, len(tt.want))
+			}
+			for name, want := range tt.want {
+				if got := report.Checks[name]; got.Status != want || (want == StatusFail) != (got.Error != "") {
+					t.Errorf("check %s = %+v, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNilCheckerIsReady(t *testing.T) {
+	var c *Checker
+	c.Drain()
+	if report := c.Run(context.Background()); report.Status != StatusOK {
+		t.Errorf("Run() = %+v, want ok", report)
+	}
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("decoding log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestMiddlewareTagsHandlerLogsAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	userID := uuid.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), userID)
+		logger.ErrorContext(r.Context(), "retrieving chirp", "error", "boom")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/123", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	Middleware(logger, mux).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "req-42" {
+		t.Fatalf("response %s = %q, want req-42", RequestIDHeader, got)
+	}
+
+	records := decodeLines(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("got %d log records, want 2", len(records))
+	}
+	for _, r := range records {
+		if r["request_id"] != "req-42" || r["user_id"] != userID.String() {
+			t.Errorf("record %v missing request or user ID", r)
+		}
+	}
+
+	access := records[1]
+	if access["msg"] != "request" || access["route"] != "GET /api/chirps/{chirpID}" || access["status"] != float64(500) || access["level"] != "ERROR" {
+		t.Errorf("access log = %v", access)
+	}
+}
+
+func TestMiddlewareReplacesInvalidRequestID(t *testing.T) {
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	})
+
+	for _, incoming := range []string{"", "bad id\nwith newline", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		rec := httptest.NewRecorder()
+		Middleware(logger, next).ServeHTTP(rec, req)
+
+		if seen == incoming || seen == "" {
+			t.Errorf("incoming %q: request ID = %q, want a generated one", incoming, seen)
+		}
+		if rec.Header().Get(RequestIDHeader) != seen {
+			t.Errorf("incoming %q: response header %q, want %q", incoming, rec.Header().Get(RequestIDHeader), seen)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package metrics exposes Chirpy's operational and domain metrics in the
+// Prometheus exposition format.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chirpy"
+
+// unmatchedRoute labels requests that did not match any registered pattern,
+// keeping arbitrary URLs out of the label set.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors registered for one server. A nil *Metrics
+// records nothing, so handlers can be exercised without a registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests       *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	inFlight       prometheus.Gauge
+	fileserverHits prometheus.Counter
+	chirpsCreated  prometheus.Counter
+	logins         *prometheus.CounterVec
+	refreshes      *prometheus.CounterVec
+}
+
+// New creates a registry with HTTP, domain, Go runtime and process metrics.
+// When dbConn is non-nil its connection pool statistics are exported too.
+func New(dbConn *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		fileserverHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fileserver_hits_total",
+			Help:      "Requests served from /app.",
+		}),
+		chirpsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps created.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by method and result.",
+		}, []string{"method", "result"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_refreshes_total",
+			Help:      "Access tokens issued from refresh tokens, by kind of session.",
+		}, []string{"kind"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.fileserverHits,
+		m.chirpsCreated,
+		m.logins,
+		m.refreshes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if dbConn != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(dbConn, namespace))
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records request counts and latency for next. It must wrap the
+// ServeMux so the matched route pattern is known once the request is served.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := routeLabel(r.Pattern)
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+		m.duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeLabel strips the method from a ServeMux pattern such as
+// "GET /api/chirps/{chirpID}", since the method is its own label.
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// FileserverHit counts a request served from /app.
+func (m *Metrics) FileserverHit() {
+	if m == nil {
+		return
+	}
+	m.fileserverHits.Inc()
+}
+
+// ChirpCreated counts a newly created chirp.
+func (m *Metrics) ChirpCreated() {
+	if m == nil {
+		return
+	}
+	m.chirpsCreated.Inc()
+}
+
+// Login counts a login attempt. method is "password" or an identity
+// provider; result is "success" or a failure reason.
+func (m *Metrics) Login(method, result string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(method, result).Inc()
+}
+
+// TokenRefreshed counts an access token issued from a refresh token. kind is
+// "session" for first-party sessions or "oauth" for OAuth clients.
+func (m *Metrics) TokenRefreshed(kind string) {
+	if m == nil {
+		return
+	}
+	m.refreshes.WithLabelValues(kind).Inc()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("reading metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	m := New(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := m.Middleware(mux)
+
+	for _, path := range []string{"/api/chirps/1", "/api/chirps/2", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`chirpy_http_requests_total{code="404",method="GET",route="/api/chirps/{chirpID}"} 2`,
+		`chirpy_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`chirpy_http_request_duration_seconds_count{method="GET",route="/api/chirps/{chirpID}"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestDomainCounters(t *testing.T) {
+	m := New(nil)
+	m.ChirpCreated()
+	m.Login("password", "success")
+	m.Login("password", "invalid_credentials")
+	m.TokenRefreshed("session")
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"chirpy_chirps_created_total 1",
+		`chirpy_logins_total{method="password",result="invalid_credentials"} 1`,
+		`chirpy_logins_total{method="password",result="success"} 1`,
+		`chirpy_token_refreshes_total{kind="session"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.ChirpCreated()
+	m.Login("password", "success")
+	m.TokenRefreshed("oauth")
+	m.FileserverHit()
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	m.Middleware(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Fatal("nil Metrics middleware did not call next")
+	}
+}
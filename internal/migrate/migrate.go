@@ -0,0 +1,51 @@
+// Package migrate applies the goose migrations embedded from sql/schema, or
+// from sql/sqlite/schema for the SQLite backend.
+//
+// PostgreSQL migrations run under a session-level advisory lock, so replicas
+// that start together apply each migration once: the first takes the lock and
+// the others wait for it, then find nothing left to do. SQLite databases have
+// a single node and need no lock.
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+
+	"chirpy/internal/storage"
+	"chirpy/sql/schema"
+	sqliteschema "chirpy/sql/sqlite/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Lock acquisition is retried every lockRetrySeconds up to lockRetries times,
+// which bounds how long a replica waits for another one's migrations.
+const (
+	lockRetrySeconds = 1
+	lockRetries      = 300
+)
+
+// NewProvider returns a goose provider for the backend's embedded migrations.
+// Versions are tracked in goose's default goose_db_version table, so
+// databases that were migrated with the goose CLI carry on where they left
+// off.
+func NewProvider(db *storage.DB) (*goose.Provider, error) {
+	dialect, migrations := goose.DialectPostgres, fs.FS(schema.FS)
+	opts := []goose.ProviderOption{goose.WithDisableGlobalRegistry(true)}
+
+	if db.Backend == storage.SQLite {
+		dialect, migrations = goose.DialectSQLite3, sqliteschema.FS
+	} else {
+		locker, err := lock.NewPostgresSessionLocker(lock.WithLockTimeout(lockRetrySeconds, lockRetries))
+		if err != nil {
+			return nil, fmt.Errorf("creating migration lock: %w", err)
+		}
+		opts = append(opts, goose.WithSessionLocker(locker))
+	}
+
+	provider, err := goose.NewProvider(dialect, db.DB, migrations, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+	return provider, nil
+}
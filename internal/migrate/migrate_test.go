@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	"chirpy/internal/storage"
+	"chirpy/sql/schema"
+	sqliteschema "chirpy/sql/sqlite/schema"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for name, migrations := range map[string]fs.FS{"postgres": schema.FS, "sqlite": sqliteschema.FS} {
+		t.Run(name, func(t *testing.T) {
+			checkMigrations(t, migrations)
+		})
+	}
+}
+
+func checkMigrations(t *testing.T, migrations fs.FS) {
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version != i+1 {
+			t.Errorf("%s: version %q, want %03d so migrations apply in order without gaps", name, prefix, i+1)
+		}
+
+		body, err := fs.ReadFile(migrations, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, annotation := range []string{"-- +goose Up", "-- +goose Down"} {
+			if !strings.Contains(string(body), annotation) {
+				t.Errorf("%s: missing %q", name, annotation)
+			}
+		}
+	}
+}
+
+func TestSQLiteMigrationsUpAndDown(t *testing.T) {
+	conn, err := storage.Open("sqlite::memory:", storage.PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	provider, err := NewProvider(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if _, err := provider.DownTo(ctx, 0); err != nil {
+		t.Fatalf("DownTo(0) error = %v", err)
+	}
+
+	var tables int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'goose%' AND name NOT LIKE 'sqlite%'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables left after migrating down", tables)
+	}
+}
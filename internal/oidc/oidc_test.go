@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a minimal OpenID provider that issues an ID token for a single
+// pending authorization code.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	m := &mockIssuer{t: t, key: key, subject: "mock-subject", email: "walt@example.com"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != "good-code" || S256Challenge(r.PostForm.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(m.nonce, "chirpy-client")})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) idToken(nonce, audience string) string {
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            m.subject,
+		"aud":            audience,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          m.email,
+		"email_verified": true,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func discoverMock(t *testing.T, m *mockIssuer) *Provider {
+	t.Helper()
+	provider, err := Discover(context.Background(), Config{
+		Name:        "mock",
+		IssuerURL:   m.server.URL,
+		ClientID:    "chirpy-client",
+		RedirectURL: "http://localhost:8080/api/auth/oidc/mock/callback",
+	}, m.server.Client())
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	return provider
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	m := newMockIssuer(t)
+	provider := discoverMock(t, m)
+
+	verifier, err := RandomString()
+	if err != nil {
+		t.Fatalf("RandomString() error = %v", err)
+	}
+	m.nonce = "nonce-123"
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state-abc", m.nonce, verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() returned invalid URL: %v", err)
+	}
+	q := authURL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("state") != "state-abc" || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("AuthCodeURL() query = %v", q)
+	}
+	m.challenge = q.Get("code_challenge")
+
+	rawIDToken, err := provider.Exchange(context.Background(), "good-code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	identity, err := provider.VerifyIDToken(context.Background(), rawIDToken, m.nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if identity.Subject != m.subject || identity.Email != m.email || !identity.EmailVerified {
+		t.Fatalf("VerifyIDToken() = %+v", identity)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockIssuer(t)
+	provider := discoverMock(t, m)
+	m.challenge = S256Challenge("expected-verifier")
+
+	if _, err := provider.Exchange(context.Background(), "good-code", "other-verifier"); err == nil {
+		t.Fatalf("Exchange() expected error for wrong PKCE verifier")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	m := newMockIssuer(t)
+	provider := discoverMock(t, m)
+
+	cases := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{name: "wrong nonce", token: m.idToken("nonce-1", "chirpy-client"), nonce: "nonce-2"},
+		{name: "wrong audience", token: m.idToken("nonce-1", "someone-else"), nonce: "nonce-1"},
+		{name: "garbage", token: "not-a-jwt", nonce: "nonce-1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := provider.VerifyIDToken(context.Background(), tc.token, tc.nonce); err == nil {
+				t.Fatalf("VerifyIDToken() expected error")
+			}
+		})
+	}
+}
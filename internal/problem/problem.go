@@ -0,0 +1,138 @@
+// Package problem writes error responses as RFC 9457 (formerly RFC 7807)
+// problem details.
+//
+// Every problem carries a machine-readable code, which clients should branch
+// on rather than the human-readable title and detail, and the ID the
+// request was logged under, so support can find it:
+//
+//	{
+//	  "type": "urn:chirpy:problem:validation_failed",
+//	  "title": "Request is invalid",
+//	  "status": 400,
+//	  "detail": "Chirp is too long",
+//	  "instance": "/api/chirps",
+//	  "code": "validation_failed",
+//	  "request_id": "5f0c…",
+//	  "errors": [{"field": "body", "code": "too_long", "detail": "Chirp is too long"}]
+//	}
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/logging"
+)
+
+// ContentType is the media type of a problem response.
+const ContentType = "application/problem+json"
+
+// TypeURIPrefix prefixes a code to form the problem's type URI. The URIs
+// identify problem types; they are not meant to be dereferenced.
+const TypeURIPrefix = "urn:chirpy:problem:"
+
+// Code identifies a kind of problem, or of field error, to clients.
+type Code string
+
+// Field error codes shared across endpoints.
+const (
+	Required Code = "required"
+	Invalid  Code = "invalid"
+	TooLong  Code = "too_long"
+	Unknown  Code = "unknown"
+)
+
+// Type is a kind of problem: its code, HTTP status and a title that stays the
+// same from one occurrence to the next.
+type Type struct {
+	Code   Code
+	Status int
+	Title  string
+}
+
+// Problem types that apply to any endpoint.
+var (
+	BadRequest           = Type{"bad_request", http.StatusBadRequest, "Bad request"}
+	InvalidBody          = Type{"invalid_body", http.StatusBadRequest, "Request body is not valid"}
+	ValidationFailed     = Type{"validation_failed", http.StatusBadRequest, "Request is invalid"}
+	Unauthorized         = Type{"unauthorized", http.StatusUnauthorized, "Authentication required"}
+	Forbidden            = Type{"forbidden", http.StatusForbidden, "Forbidden"}
+	NotFound             = Type{"not_found", http.StatusNotFound, "Not found"}
+	Conflict             = Type{"conflict", http.StatusConflict, "Conflict"}
+	PayloadTooLarge      = Type{"payload_too_large", http.StatusRequestEntityTooLarge, "Request body is too large"}
+	UnsupportedMediaType = Type{"unsupported_media_type", http.StatusUnsupportedMediaType, "Unsupported media type"}
+	TooManyRequests      = Type{"rate_limited", http.StatusTooManyRequests, "Too many requests"}
+	Internal             = Type{"internal_error", http.StatusInternalServerError, "Internal server error"}
+	Unavailable          = Type{"unavailable", http.StatusServiceUnavailable, "Service unavailable"}
+)
+
+var byStatus = map[int]Type{}
+
+func init() {
+	for _, t := range []Type{BadRequest, Unauthorized, Forbidden, NotFound, Conflict, PayloadTooLarge, UnsupportedMediaType, TooManyRequests, Internal, Unavailable} {
+		byStatus[t.Status] = t
+	}
+}
+
+// ForStatus returns the generic problem type for an HTTP status. Statuses
+// without one get a code and title from the status text, e.g.
+// "unprocessable_entity".
+func ForStatus(status int) Type {
+	if t, ok := byStatus[status]; ok {
+		return t
+	}
+	text := http.StatusText(status)
+	code := strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+	return Type{Code(code), status, text}
+}
+
+// FieldError reports what is wrong with one field of the request.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   Code   `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// Problem is the body of a problem response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// New returns the problem of type t that occurred while serving r. detail
+// explains this occurrence and may be empty.
+func New(r *http.Request, t Type, detail string, errs ...FieldError) Problem {
+	return Problem{
+		Type:      TypeURIPrefix + string(t.Code),
+		Title:     t.Title,
+		Status:    t.Status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      t.Code,
+		RequestID: logging.RequestID(r.Context()),
+		Errors:    errs,
+	}
+}
+
+// Write responds to r with a problem of type t.
+func Write(w http.ResponseWriter, r *http.Request, t Type, detail string, errs ...FieldError) {
+	p := New(r, t, detail, errs...)
+	data, err := json.Marshal(p)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "marshalling problem", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(data)
+}
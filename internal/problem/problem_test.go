@@ -0,0 +1,66 @@
+package problem
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"chirpy/internal/logging"
+)
+
+func TestForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   Type
+	}{
+		{http.StatusNotFound, NotFound},
+		{http.StatusTooManyRequests, TooManyRequests},
+		{http.StatusUnprocessableEntity, Type{"unprocessable_entity", http.StatusUnprocessableEntity, "Unprocessable Entity"}},
+		{http.StatusTeapot, Type{"im_a_teapot", http.StatusTeapot, "I'm a teapot"}},
+	}
+	for _, tt := range tests {
+		if got := ForStatus(tt.status); got != tt.want {
+			t.Errorf("ForStatus(%d) = %+v, want %+v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	handler := logging.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, ValidationFailed, "Chirp is too long", FieldError{Field: "body", Code: TooLong, Detail: "Chirp is too long"})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+	req.Header.Set(logging.RequestIDHeader, "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+
+	var got Problem
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{
+		Type:      "urn:chirpy:problem:validation_failed",
+		Title:     ValidationFailed.Title,
+		Status:    http.StatusBadRequest,
+		Detail:    "Chirp is too long",
+		Instance:  "/api/chirps",
+		Code:      "validation_failed",
+		RequestID: "req-123",
+		Errors:    []FieldError{{Field: "body", Code: TooLong, Detail: "Chirp is too long"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problem = %+v, want %+v", got, want)
+	}
+}
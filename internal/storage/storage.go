@@ -0,0 +1,253 @@
+// Package storage opens the database named by DB_URL and picks the backend
+// from its scheme: sqlite: URLs use SQLite, anything else PostgreSQL.
+//
+// PostgreSQL connections go through a pgx connection pool sized by
+// PoolConfig. SQLite suits single-node and development deployments:
+//
+//	sqlite:chirpy.db              relative to the working directory
+//	sqlite:///var/lib/chirpy.db   absolute path
+//	sqlite::memory:               in memory, gone when the process exits
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	db "chirpy/internal/database"
+	"chirpy/internal/database/sqlite"
+	"chirpy/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Backend names a database engine. Values match OpenTelemetry's
+// db.system.name.
+type Backend string
+
+const (
+	Postgres Backend = "postgresql"
+	SQLite   Backend = "sqlite"
+)
+
+// PoolConfig tunes the PostgreSQL connection pool. Zero MaxConns and
+// MaxConnIdleTime keep pgx's defaults; SQLite ignores the pool settings.
+type PoolConfig struct {
+	MaxConns        int32
+	MaxConnIdleTime time.Duration
+	// StatementCacheCapacity is how many prepared statements each connection
+	// keeps. Zero disables preparing statements, as poolers such as PgBouncer
+	// in transaction mode require.
+	StatementCacheCapacity int
+}
+
+// DefaultPoolConfig suits a single server instance.
+var DefaultPoolConfig = PoolConfig{
+	MaxConns:               10,
+	MaxConnIdleTime:        5 * time.Minute,
+	StatementCacheCapacity: 512,
+}
+
+func (p PoolConfig) apply(config *pgxpool.Config) {
+	if p.MaxConns > 0 {
+		config.MaxConns = p.MaxConns
+	}
+	if p.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = p.MaxConnIdleTime
+	}
+	config.ConnConfig.StatementCacheCapacity = p.StatementCacheCapacity
+	if p.StatementCacheCapacity == 0 {
+		config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+		config.ConnConfig.DescriptionCacheCapacity = 0
+	}
+}
+
+// DB is a connection pool together with the backend it talks to.
+type DB struct {
+	*sql.DB
+	Backend Backend
+	// pool backs DB for PostgreSQL.
+	pool *pgxpool.Pool
+}
+
+// Open connects to dbURL and checks the connection.
+func Open(dbURL string, pool PoolConfig) (*DB, error) {
+	if dbURL == "" {
+		return nil, errors.New("database URL not set (DB_URL or database.url)")
+	}
+
+	backend, dsn, err := parseURL(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var d *DB
+	if backend == SQLite {
+		conn, err := sql.Open("sqlite3", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("error opening database: %w", err)
+		}
+		// SQLite allows one writer at a time; a single connection queues
+		// writes in the pool instead of failing them with SQLITE_BUSY, and
+		// keeps an in-memory database alive between queries.
+		conn.SetMaxOpenConns(1)
+		d = &DB{DB: conn, Backend: SQLite}
+	} else {
+		config, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing database URL: %w", err)
+		}
+		pool.apply(config)
+		p, err := pgxpool.NewWithConfig(context.Background(), config)
+		if err != nil {
+			return nil, fmt.Errorf("error opening database: %w", err)
+		}
+		d = &DB{DB: stdlib.OpenDBFromPool(p), Backend: Postgres, pool: p}
+	}
+
+	if err := d.Ping(); err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
+	}
+
+	return d, nil
+}
+
+// Close closes the database and, for PostgreSQL, its connection pool.
+func (d *DB) Close() error {
+	err := d.DB.Close()
+	if d.pool != nil {
+		d.pool.Close()
+	}
+	return err
+}
+
+// PoolStats is a snapshot of the connection pool.
+type PoolStats struct {
+	MaxConns      int
+	TotalConns    int
+	IdleConns     int
+	AcquiredConns int
+	// WaitCount is how many times a query waited for a free connection
+	// since the pool opened, and WaitDuration how long in total.
+	WaitCount    int64
+	WaitDuration time.Duration
+}
+
+// PoolStats reports how busy the connection pool is.
+func (d *DB) PoolStats() PoolStats {
+	if d.pool == nil {
+		s := d.Stats()
+		return PoolStats{
+			MaxConns:      s.MaxOpenConnections,
+			TotalConns:    s.OpenConnections,
+			IdleConns:     s.Idle,
+			AcquiredConns: s.InUse,
+			WaitCount:     s.WaitCount,
+			WaitDuration:  s.WaitDuration,
+		}
+	}
+
+	s := d.pool.Stat()
+	return PoolStats{
+		MaxConns:      int(s.MaxConns()),
+		TotalConns:    int(s.TotalConns()),
+		IdleConns:     int(s.IdleConns()),
+		AcquiredConns: int(s.AcquiredConns()),
+		WaitCount:     s.EmptyAcquireCount(),
+		WaitDuration:  s.EmptyAcquireWaitTime(),
+	}
+}
+
+// Queries returns the backend's queries running on conn, which is the pool
+// itself or a wrapper around it. Their errors are classified and statements
+// that fail with db.ErrSerialization are retried; use InTx for transactions.
+func (d *DB) Queries(conn db.DBTX) db.Querier {
+	return db.Wrap(d.queries(conn), db.DefaultRetryPolicy)
+}
+
+// InTx runs fn in a transaction, committing if it returns nil. The whole
+// transaction is retried if it fails with db.ErrSerialization, so fn must
+// be safe to run more than once. Its statements are traced like those of
+// the pool.
+func (d *DB) InTx(ctx context.Context, fn func(db.Querier) error) error {
+	return db.DefaultRetryPolicy.Do(ctx, func() error {
+		tx, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		if err := fn(db.Wrap(d.queries(tracing.WrapDB(tx, string(d.Backend))), db.RetryPolicy{})); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (d *DB) queries(conn db.DBTX) db.Querier {
+	if d.Backend == SQLite {
+		return sqlite.NewStore(conn)
+	}
+	return db.New(conn)
+}
+
+func parseURL(dbURL string) (Backend, string, error) {
+	scheme, rest, ok := strings.Cut(dbURL, ":")
+	if !ok || strings.ContainsAny(scheme, "= ") {
+		// A key/value connection string such as "host=localhost dbname=chirpy".
+		return Postgres, dbURL, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Postgres, dbURL, nil
+	case "sqlite", "sqlite3":
+		dsn, err := sqliteDSN(rest)
+		if err != nil {
+			return "", "", err
+		}
+		return SQLite, dsn, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database URL scheme %q (want postgres or sqlite)", scheme)
+	}
+}
+
+// sqliteDSN turns the part of a sqlite: URL after the scheme into a
+// go-sqlite3 DSN. Query parameters pass through to the driver, except that
+// foreign keys are always enforced, as the schema relies on cascading deletes.
+func sqliteDSN(rest string) (string, error) {
+	path, rawQuery, _ := strings.Cut(strings.TrimPrefix(rest, "//"), "?")
+	if path == "" {
+		return "", errors.New("sqlite database URL has no path")
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("parsing sqlite database URL: %w", err)
+	}
+	query.Set("_foreign_keys", "on")
+	setDefault(query, "_busy_timeout", "5000")
+	// Take the write lock when a transaction begins, so transactions that
+	// read before writing cannot deadlock on upgrading their lock.
+	setDefault(query, "_txlock", "immediate")
+	if path != ":memory:" {
+		setDefault(query, "_journal_mode", "WAL")
+	}
+
+	return "file:" + path + "?" + query.Encode(), nil
+}
+
+func setDefault(query url.Values, key, value string) {
+	if !query.Has(key) {
+		query.Set(key, value)
+	}
+}
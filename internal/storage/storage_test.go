@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	db "chirpy/internal/database"
+	"chirpy/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		backend Backend
+		dsn     string
+		wantErr bool
+	}{
+		{url: "postgres://chirpy@localhost:5432/chirpy?sslmode=disable", backend: Postgres, dsn: "postgres://chirpy@localhost:5432/chirpy?sslmode=disable"},
+		{url: "postgresql://localhost/chirpy", backend: Postgres, dsn: "postgresql://localhost/chirpy"},
+		{url: "host=localhost dbname=chirpy", backend: Postgres, dsn: "host=localhost dbname=chirpy"},
+		{url: "sqlite:chirpy.db", backend: SQLite, dsn: "file:chirpy.db?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate"},
+		{url: "sqlite:///var/lib/chirpy.db", backend: SQLite, dsn: "file:/var/lib/chirpy.db?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate"},
+		{url: "sqlite3:chirpy.db?_busy_timeout=100&_foreign_keys=off", backend: SQLite, dsn: "file:chirpy.db?_busy_timeout=100&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate"},
+		{url: "sqlite::memory:", backend: SQLite, dsn: "file::memory:?_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"},
+		{url: "sqlite:", wantErr: true},
+		{url: "mysql://localhost/chirpy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			backend, dsn, err := parseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if backend != tt.backend || dsn != tt.dsn {
+				t.Errorf("parseURL() = %q, %q, want %q, %q", backend, dsn, tt.backend, tt.dsn)
+			}
+		})
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	conn, err := Open("sqlite::memory:", PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Backend != SQLite {
+		t.Fatalf("Backend = %q, want %q", conn.Backend, SQLite)
+	}
+
+	var enabled bool
+	if err := conn.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil || !enabled {
+		t.Errorf("foreign keys enabled = %v, %v", enabled, err)
+	}
+	if _, ok := conn.queries(conn).(*db.Queries); ok {
+		t.Error("SQLite connection returned PostgreSQL queries")
+	}
+}
+
+func TestInTxTracesQueries(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{ServiceName: "chirpy-test", Exporter: tracing.ExporterConsole, Writer: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := Open("sqlite::memory:", PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec("CREATE TABLE login_throttles (key TEXT PRIMARY KEY, failures INTEGER NOT NULL, locked_until TIMESTAMP, updated_at TIMESTAMP NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = conn.InTx(context.Background(), func(q db.Querier) error {
+		_, err := q.GetLoginThrottle(context.Background(), "ip:1")
+		return err
+	})
+	if !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("InTx() error = %v, want db.ErrNotFound", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `"Name":"GetLoginThrottle"`) {
+		t.Errorf("no span for the query run in the transaction; got %s", buf.String())
+	}
+}
+
+func TestPoolConfigApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     PoolConfig
+		maxConns int32
+		mode     pgx.QueryExecMode
+	}{
+		{name: "default", pool: DefaultPoolConfig, maxConns: 10, mode: pgx.QueryExecModeCacheStatement},
+		{name: "statement cache disabled", pool: PoolConfig{MaxConns: 3}, maxConns: 3, mode: pgx.QueryExecModeDescribeExec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := pgxpool.ParseConfig("postgres://chirpy@localhost/chirpy?pool_max_conns=50")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.pool.apply(config)
+			if config.MaxConns != tt.maxConns || config.ConnConfig.DefaultQueryExecMode != tt.mode {
+				t.Errorf("MaxConns = %d, mode = %v, want %d, %v", config.MaxConns, config.ConnConfig.DefaultQueryExecMode, tt.maxConns, tt.mode)
+			}
+			if config.ConnConfig.StatementCacheCapacity != tt.pool.StatementCacheCapacity {
+				t.Errorf("StatementCacheCapacity = %d, want %d", config.ConnConfig.StatementCacheCapacity, tt.pool.StatementCacheCapacity)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DBTX matches the interface sqlc generates for database.New.
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// WrapDB returns a DBTX that records a client span for every query, named
+// after the sqlc query ("-- name: GetChirp :one" becomes "GetChirp").
+// Spans cover executing the query, not reading rows from a result set.
+// system is the db.system.name attribute, such as "postgresql" or "sqlite".
+func WrapDB(db DBTX, system string) DBTX {
+	return &tracedDB{db: db, tracer: otel.Tracer(instrumentationName), system: semconv.DBSystemNameKey.String(system)}
+}
+
+type tracedDB struct {
+	db     DBTX
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name, kind := queryName(query)
+	attrs := []attribute.KeyValue{
+		t.system,
+		semconv.DBQueryText(query),
+	}
+	if kind != "" {
+		attrs = append(attrs, attribute.String("db.sqlc.command", kind))
+	}
+	return t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	end(span, err)
+	return result, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	stmt, err := t.db.PrepareContext(ctx, query)
+	end(span, err)
+	return stmt, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	end(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	end(span, row.Err())
+	return row
+}
+
+// queryName extracts the name and command from sqlc's leading
+// "-- name: <Name> :<command>" comment. Other queries are named "db.query".
+func queryName(query string) (string, string) {
+	header, _, _ := strings.Cut(query, "\n")
+	rest, ok := strings.CutPrefix(strings.TrimSpace(header), "-- name:")
+	if !ok {
+		return "db.query", ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "db.query", ""
+	}
+	var kind string
+	if len(fields) > 1 {
+		kind = strings.TrimPrefix(fields[1], ":")
+	}
+	return fields[0], kind
+}
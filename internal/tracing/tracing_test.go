@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeDB struct{}
+
+func (fakeDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (fakeDB) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+}
+
+func TestSpansForRequestAndQuery(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "chirpy-test", Exporter: ExporterConsole, Writer: &buf})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	queries := WrapDB(fakeDB{}, "postgresql")
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = queries.ExecContext(r.Context(), "-- name: DeleteChirp :exec\nDELETE FROM chirps\nWHERE id = $1\n", r.PathValue("chirpID"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	// Like the logging middleware, hide the pattern from otelhttp by passing
+	// a copy of the request down the chain.
+	copying := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NameByRoute(mux).ServeHTTP(w, r.WithContext(r.Context()))
+	})
+	Middleware(copying).ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	spans := map[string]exportedSpan{}
+	dec := json.NewDecoder(&buf)
+	for {
+		var s exportedSpan
+		if err := dec.Decode(&s); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("decoding spans: %v", err)
+		}
+		spans[s.Name] = s
+	}
+
+	server, ok := spans["DELETE /api/chirps/{chirpID}"]
+	if !ok {
+		t.Fatalf("no span named after the route; got %v", spans)
+	}
+	if server.SpanContext.TraceID != traceID || server.Parent.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("server span did not continue the incoming trace: %+v", server)
+	}
+
+	query, ok := spans["DeleteChirp"]
+	if !ok {
+		t.Fatalf("no span named after the query; got %v", spans)
+	}
+	if query.Parent.SpanID != server.SpanContext.SpanID || query.SpanContext.TraceID != traceID {
+		t.Errorf("query span is not a child of the server span: %+v", query)
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantName string
+		wantKind string
+	}{
+		{query: "-- name: GetChirp :one\nSELECT 1", wantName: "GetChirp", wantKind: "one"},
+		{query: "-- name: DeleteUserEntitlement :execrows\nDELETE", wantName: "DeleteUserEntitlement", wantKind: "execrows"},
+		{query: "SELECT 1", wantName: "db.query"},
+	}
+	for _, tt := range tests {
+		name, kind := queryName(tt.query)
+		if name != tt.wantName || kind != tt.wantKind {
+			t.Errorf("queryName(%q) = %q, %q, want %q, %q", tt.query, name, kind, tt.wantName, tt.wantKind)
+		}
+	}
+}
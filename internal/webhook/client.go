@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrDisallowedAddress is returned when a delivery URL resolves to an address
+// that outgoing webhooks may not reach.
+var ErrDisallowedAddress = errors.New("webhook: destination address not allowed")
+
+// NewClient returns an HTTP client for delivering webhooks to user-supplied
+// URLs. Unless allowPrivate is set, it refuses to connect to loopback,
+// private, link-local and other non-public addresses, checked after DNS
+// resolution so that a hostname cannot be pointed at internal services.
+// Redirects are not followed.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrDisallowedAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// IsPublicAddr reports whether addr is a globally routable unicast address.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	// Carrier-grade NAT, 100.64.0.0/10, is not covered by IsPrivate.
+	return !netip.MustParsePrefix("100.64.0.0/10").Contains(addr)
+}
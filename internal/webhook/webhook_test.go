@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"event":"user.upgraded"}`)
+	sent := time.Unix(1_700_000_000, 0)
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+	signature := Sign(secret, sent, body)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "valid", timestamp: timestamp, signature: signature, body: body, now: sent.Add(time.Minute)},
+		{name: "rotated secrets", timestamp: timestamp, signature: "v1=00ff, " + signature, body: body, now: sent},
+		{name: "tampered body", timestamp: timestamp, signature: signature, body: []byte(`{"event":"user.downgraded"}`), now: sent, wantErr: ErrInvalidSignature},
+		{name: "wrong secret", timestamp: timestamp, signature: Sign([]byte("other"), sent, body), body: body, now: sent, wantErr: ErrInvalidSignature},
+		{name: "unknown version", timestamp: timestamp, signature: "v0" + signature[2:], body: body, now: sent, wantErr: ErrInvalidSignature},
+		{name: "replayed", timestamp: timestamp, signature: signature, body: body, now: sent.Add(DefaultTolerance + time.Second), wantErr: ErrTimestampOutOfRange},
+		{name: "from the future", timestamp: timestamp, signature: signature, body: body, now: sent.Add(-DefaultTolerance - time.Second), wantErr: ErrTimestampOutOfRange},
+		{name: "missing signature", timestamp: timestamp, body: body, now: sent, wantErr: ErrMissingSignature},
+		{name: "malformed timestamp", timestamp: "yesterday", signature: signature, body: body, now: sent, wantErr: ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(secret, tt.timestamp, tt.signature, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
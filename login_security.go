@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"chirpy/internal/auth"
+	db "chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	loginFailureInvalidCredentials = "invalid_credentials"
+	loginFailureLocked             = "locked"
+)
+
+// loginThrottle identifies one login failure counter and the policy applied to it.
+type loginThrottle struct {
+	key    string
+	policy auth.LockoutPolicy
+}
+
+// loginThrottles returns the per-account and per-IP counters for a login
+// attempt. Accounts are keyed by email rather than user ID so that unknown
+// addresses are throttled exactly like real ones.
+func (cfg *apiConfig) loginThrottles(email, ip string) []loginThrottle {
+	return []loginThrottle{
+		{key: accountThrottleKey(email), policy: cfg.accountLockout},
+		{key: "ip:" + ip, policy: cfg.ipLockout},
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// loginOutcome is the result of checking a password login.
+type loginOutcome struct {
+	// lockedUntil is set when a throttle was locked, and nothing was checked.
+	lockedUntil time.Time
+	// userID is the account the email belongs to, if any.
+	userID uuid.NullUUID
+	// user is set when the password matched.
+	user db.User
+	ok   bool
+}
+
+// verifyLogin checks a password login against its throttles. The attempt is
+// counted as a failure before the password is checked, in a short
+// transaction that locks the throttle rows, so concurrent guesses cannot all
+// pass the lockout check before any is counted. The password is hashed after
+// that transaction commits, and a login that succeeds takes its count back.
+func (cfg *apiConfig) verifyLogin(ctx context.Context, email, password string, throttles []loginThrottle) (loginOutcome, error) {
+	var out loginOutcome
+	lockedUntil, err := cfg.reserveLoginAttempt(ctx, throttles)
+	if err != nil || !lockedUntil.IsZero() {
+		out.lockedUntil = lockedUntil
+		return out, err
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			return out, fmt.Errorf("retrieving user by email: %w", err)
+		}
+		auth.CompareDummyPassword(password)
+		return out, nil
+	}
+	out.userID = uuid.NullUUID{UUID: dbUser.ID, Valid: true}
+
+	match, err := auth.CheckPasswordHash(password, dbUser.HashedPassword)
+	if err != nil {
+		slog.ErrorContext(ctx, "comparing password hash", "error", err)
+	}
+	if err != nil || !match {
+		return out, nil
+	}
+	out.user, out.ok = dbUser, true
+	return out, cfg.releaseLoginAttempt(ctx, email, throttles)
+}
+
+// reserveLoginAttempt counts a login attempt as a failure against every
+// throttle, unless one of them is locked, in which case nothing is counted
+// and the latest lock expiry is returned.
+func (cfg *apiConfig) reserveLoginAttempt(ctx context.Context, throttles []loginThrottle) (time.Time, error) {
+	var lockedUntil time.Time
+	err := cfg.tx.InTx(ctx, func(q db.Querier) error {
+		lockedUntil = time.Time{}
+		now := time.Now().UTC()
+
+		// Throttles are always locked in the same order, so that two logins
+		// cannot each hold the lock the other is waiting for.
+		for _, t := range throttles {
+			row, err := q.LockLoginThrottle(ctx, t.key)
+			if err != nil {
+				return fmt.Errorf("locking login throttle: %w", err)
+			}
+			if row.LockedUntil.Valid && row.LockedUntil.Time.After(now) && row.LockedUntil.Time.After(lockedUntil) {
+				lockedUntil = row.LockedUntil.Time
+			}
+		}
+		if !lockedUntil.IsZero() {
+			return nil
+		}
+		return recordLoginFailure(ctx, q, throttles)
+	})
+	return lockedUntil, err
+}
+
+// releaseLoginAttempt takes back the failure reserveLoginAttempt counted for
+// a login that succeeded. The per-account counter is reset, but the others
+// only lose this attempt, so that an attacker holding one valid account
+// cannot use it to reset their guessing budget.
+func (cfg *apiConfig) releaseLoginAttempt(ctx context.Context, email string, throttles []loginThrottle) error {
+	return cfg.tx.InTx(ctx, func(q db.Querier) error {
+		for _, t := range throttles {
+			if t.key == accountThrottleKey(email) {
+				if err := q.ClearLoginThrottle(ctx, t.key); err != nil {
+					return fmt.Errorf("clearing login throttle: %w", err)
+				}
+				continue
+			}
+
+			row, err := q.ForgiveLoginFailure(ctx, t.key)
+			if errors.Is(err, db.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("forgiving login failure: %w", err)
+			}
+			// The lock this attempt set when it was counted no longer applies.
+			if row.LockedUntil.Valid && t.policy.LockoutFor(int(row.Failures)) == 0 {
+				if err := q.SetLoginLockout(ctx, db.SetLoginLockoutParams{Key: t.key}); err != nil {
+					return fmt.Errorf("unlocking login throttle: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// recordLoginFailure bumps every throttle and applies exponential backoff to
+// those that crossed their policy threshold.
+func recordLoginFailure(ctx context.Context, q db.Querier, throttles []loginThrottle) error {
+	now := time.Now().UTC()
+
+	for _, t := range throttles {
+		row, err := q.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Key:         t.key,
+			WindowStart: now.Add(-t.policy.Window),
+		})
+		if err != nil {
+			return fmt.Errorf("recording login failure: %w", err)
+		}
+
+		delay := t.policy.LockoutFor(int(row.Failures))
+		if delay == 0 {
+			continue
+		}
+
+		if err := q.SetLoginLockout(ctx, db.SetLoginLockoutParams{
+			Key:         t.key,
+			LockedUntil: sql.NullTime{Time: now.Add(delay), Valid: true},
+		}); err != nil {
+			return fmt.Errorf("locking login throttle: %w", err)
+		}
+	}
+	return nil
+}
+
+// upgradePasswordHash rehashes a verified password when its stored hash was
+// created with weaker Argon2id parameters than are currently configured.
+func (cfg *apiConfig) upgradePasswordHash(ctx context.Context, dbUser db.User, password string) {
+	needsRehash, err := auth.NeedsRehash(dbUser.HashedPassword)
+	if err != nil {
+		slog.ErrorContext(ctx, "decoding password hash", "user_id", dbUser.ID, "error", err)
+		return
+	}
+	if !needsRehash {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		slog.ErrorContext(ctx, "rehashing password", "user_id", dbUser.ID, "error", err)
+		return
+	}
+
+	if err := cfg.dbQueries.UpdateUserPasswordHash(ctx, db.UpdateUserPasswordHashParams{
+		ID:             dbUser.ID,
+		HashedPassword: hashedPassword,
+	}); err != nil {
+		slog.ErrorContext(ctx, "storing rehashed password", "user_id", dbUser.ID, "error", err)
+	}
+}
+
+// recordLoginAttempt appends an entry to the login audit trail.
+func (cfg *apiConfig) recordLoginAttempt(r *http.Request, email string, userID uuid.NullUUID, failureReason string) {
+	result := failureReason
+	if result == "" {
+		result = "success"
+	}
+	cfg.metrics.Login("password", result)
+
+	err := cfg.dbQueries.CreateLoginAttempt(r.Context(), db.CreateLoginAttemptParams{
+		Email:         strings.ToLower(email),
+		UserID:        userID,
+		IpAddress:     clientIP(r),
+		UserAgent:     r.UserAgent(),
+		Succeeded:     failureReason == "",
+		FailureReason: sql.NullString{String: failureReason, Valid: failureReason != ""},
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "recording login attempt", "error", err)
+	}
+}
+
+// clientIP returns the address of the directly connected client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
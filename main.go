@@ -1,32 +1,64 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 	"unicode"
 
 	"chirpy/internal/auth"
+	"chirpy/internal/config"
 	db "chirpy/internal/database"
+	"chirpy/internal/entitlements"
+	"chirpy/internal/health"
+	"chirpy/internal/logging"
+	"chirpy/internal/metrics"
+	"chirpy/internal/migrate"
+	"chirpy/internal/oidc"
+	"chirpy/internal/problem"
+	"chirpy/internal/storage"
+	"chirpy/internal/tracing"
+	"chirpy/internal/webhook"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	pq "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	dbQueries      *db.Queries
-	platform       string
-	jwtSecret      string
-	polkaKey       string
+	dbQueries      db.Querier
+	// tx runs work that must commit as a whole against the same database.
+	tx        db.Transactor
+	platform  string
+	jwtSecret string
+	polkaKey  string
+	// polkaWebhookSecret, when set, replaces polkaKey with HMAC signatures.
+	polkaWebhookSecret []byte
+	accountLockout     auth.LockoutPolicy
+	ipLockout          auth.LockoutPolicy
+	passwordPolicy     auth.PasswordPolicy
+	oidcProviders      map[string]*oidc.Provider
+	metrics            *metrics.Metrics
+	// readiness runs the checks behind /api/readyz.
+	readiness *health.Checker
+	// webhookWorkerSeen is when the webhook delivery worker last made
+	// progress, in Unix nanoseconds.
+	webhookWorkerSeen atomic.Int64
 }
 
 type User struct {
@@ -35,20 +67,39 @@ type User struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Role        string    `json:"role"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Body        string    `json:"body"`
+	UserID      uuid.UUID `json:"user_id"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+func chirpResponse(c db.Chirp) Chirp {
+	return Chirp{
+		ID:          c.ID,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+		Body:        c.Body,
+		UserID:      c.UserID,
+		PublishedAt: c.PublishedAt,
+	}
+}
+
+// chirpVisible reports whether viewer may see c at now. Scheduled chirps are
+// only shown to their author until they are published.
+func chirpVisible(c db.Chirp, viewer uuid.UUID, now time.Time) bool {
+	return !c.PublishedAt.After(now) || (viewer != uuid.Nil && c.UserID == viewer)
 }
 
 func respondWithJSON(w http.ResponseWriter, status int, payload any) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("error marshalling JSON: %v", err)
+		slog.Error("marshalling JSON", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -58,8 +109,42 @@ func respondWithJSON(w http.ResponseWriter, status int, payload any) {
 	_, _ = w.Write(data)
 }
 
-func respondWithError(w http.ResponseWriter, status int, message string) {
-	respondWithJSON(w, status, map[string]string{"error": message})
+// respondWithError responds with the generic problem for status; see
+// problem.Write for problems clients need to tell apart.
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	problem.Write(w, r, problem.ForStatus(status), detail)
+}
+
+// respondWithInvalidField rejects a request because of one field, named as
+// the client sent it: a JSON property or a query parameter.
+func respondWithInvalidField(w http.ResponseWriter, r *http.Request, field string, code problem.Code, detail string) {
+	problem.Write(w, r, problem.ValidationFailed, detail, problem.FieldError{Field: field, Code: code, Detail: detail})
+}
+
+// Problems specific to chirpy's endpoints.
+var (
+	problemInvalidCredentials = problem.Type{Code: "invalid_credentials", Status: http.StatusUnauthorized, Title: "Incorrect email or password"}
+	problemEmailTaken         = problem.Type{Code: "email_taken", Status: http.StatusConflict, Title: "Email already exists"}
+	problemLoginLocked        = problem.Type{Code: "login_locked", Status: http.StatusTooManyRequests, Title: "Too many failed login attempts"}
+	problemChirpRateLimited   = problem.Type{Code: "chirp_rate_limited", Status: http.StatusTooManyRequests, Title: "Chirp rate limit exceeded"}
+	problemPlanRequired       = problem.Type{Code: "plan_required", Status: http.StatusForbidden, Title: "Not available on your plan"}
+)
+
+// respondWithDBError maps a database error the handler did not expect to a
+// problem by its class, falling back to a 500 with detail. Transient
+// failures that survived the query retries become a 503 the client may retry.
+func respondWithDBError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case errors.Is(err, db.ErrSerialization), errors.Is(err, db.ErrTimeout):
+		w.Header().Set("Retry-After", "1")
+		problem.Write(w, r, problem.Unavailable, "Database busy, try again")
+	case errors.Is(err, db.ErrConflict):
+		problem.Write(w, r, problem.Conflict, "")
+	case errors.Is(err, db.ErrNotFound):
+		problem.Write(w, r, problem.NotFound, "")
+	default:
+		problem.Write(w, r, problem.Internal, detail)
+	}
 }
 
 var profaneWords = map[string]struct{}{
@@ -99,9 +184,49 @@ func sanitizeChirp(body string) string {
 	return sanitized.String()
 }
 
+// loadPrincipal fills in account details for an authenticated request.
+func (cfg *apiConfig) loadPrincipal(ctx context.Context, p *auth.Principal) error {
+	dbUser, err := cfg.dbQueries.GetUser(ctx, p.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return auth.ErrPrincipalNotFound
+		}
+		return err
+	}
+
+	logging.SetUserID(ctx, dbUser.ID)
+	p.Role = auth.Role(dbUser.Role)
+	p.IsChirpyRed, err = cfg.isChirpyRed(ctx, dbUser.ID)
+	if err != nil {
+		return err
+	}
+
+	// Tokens issued to a deleted OAuth client stop working immediately.
+	if p.ClientID != "" {
+		clientID, err := uuid.Parse(p.ClientID)
+		if err != nil {
+			return auth.ErrPrincipalNotFound
+		}
+		if _, err := cfg.dbQueries.GetOAuthClient(ctx, clientID); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				return auth.ErrPrincipalNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionCookieOptions returns the cookie attributes for browser sessions.
+// Cookies are only sent over HTTPS outside local development.
+func (cfg *apiConfig) sessionCookieOptions() auth.SessionCookieOptions {
+	return auth.SessionCookieOptions{Secure: cfg.platform != config.PlatformDev}
+}
+
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHits.Add(1)
+		cfg.metrics.FileserverHit()
 		next.ServeHTTP(w, r)
 	})
 }
@@ -120,14 +245,14 @@ func (cfg *apiConfig) adminMetricsHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
-	if cfg.platform != "dev" {
-		respondWithError(w, http.StatusForbidden, "forbidden")
+	if cfg.platform != config.PlatformDev {
+		respondWithError(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
 	if err := cfg.dbQueries.DeleteUsers(r.Context()); err != nil {
-		log.Printf("error deleting users: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not reset users")
+		slog.ErrorContext(r.Context(), "deleting users", "error", err)
+		respondWithDBError(w, r, err, "Could not reset users")
 		return
 	}
 
@@ -137,102 +262,301 @@ func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("OK"))
 }
 
-func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Body string `json:"body"`
+		Role string `json:"role"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	userID, err := uuid.Parse(r.PathValue("userID"))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	var params requestBody
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithInvalidField(w, r, "role", problem.Invalid, "Invalid role")
+		return
+	}
+
+	if userID == principal.UserID && role != auth.RoleAdmin {
+		respondWithError(w, r, http.StatusBadRequest, "Admins cannot demote themselves")
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.SetUserRole(r.Context(), db.SetUserRoleParams{
+		ID:   userID,
+		Role: string(role),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "setting role", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update role")
+		return
+	}
+
+	user, err := cfg.userResponse(r.Context(), dbUser)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		slog.ErrorContext(r.Context(), "loading user", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update role")
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// errChirpRateLimited aborts creating a chirp whose author is over their
+// chirps-per-hour limit.
+var errChirpRateLimited = errors.New("chirp rate limit exceeded")
+
+func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Body      string     `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID := principal.UserID
+
 	var params requestBody
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
 	if len(params.Body) == 0 {
-		respondWithError(w, http.StatusBadRequest, "body is required")
+		respondWithInvalidField(w, r, "body", problem.Required, "body is required")
 		return
 	}
 
-	if len(params.Body) > 140 {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
+	ents, _, err := cfg.entitlementsFor(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading entitlements", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not create chirp")
 		return
 	}
 
+	if len(params.Body) > ents.ChirpLength() {
+		respondWithInvalidField(w, r, "body", problem.TooLong, "Chirp is too long")
+		return
+	}
+
+	now := time.Now().UTC()
+	var publishAt sql.NullTime
+	if params.PublishAt != nil && params.PublishAt.After(now) {
+		if !ents.Has(entitlements.FeatureScheduledChirps) {
+			problem.Write(w, r, problemPlanRequired, "Scheduled chirps are not available on your plan")
+			return
+		}
+		publishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
+
 	cleaned := sanitizeChirp(params.Body)
 
-	dbChirp, err := cfg.dbQueries.CreateChirp(r.Context(), db.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
+	// The rate limit counts chirp posts rather than chirps, so that deleting
+	// chirps does not hand back quota.
+	windowStart := now.Add(-entitlements.ChirpRateWindow)
+	var chirp Chirp
+	err = cfg.tx.InTx(r.Context(), func(q db.Querier) error {
+		// Concurrent posts by one user would otherwise all count the same
+		// posts and could together exceed the limit.
+		if err := q.LockUser(r.Context(), userID); err != nil {
+			return err
+		}
+		if err := q.DeleteChirpPostsBefore(r.Context(), db.DeleteChirpPostsBeforeParams{UserID: userID, CreatedAt: windowStart}); err != nil {
+			return err
+		}
+		recent, err := q.CountChirpPostsSince(r.Context(), db.CountChirpPostsSinceParams{UserID: userID, CreatedAt: windowStart})
+		if err != nil {
+			return err
+		}
+		if recent >= int64(ents.ChirpsPerHour()) {
+			return errChirpRateLimited
+		}
+
+		dbChirp, err := q.CreateChirp(r.Context(), db.CreateChirpParams{
+			Body:        cleaned,
+			UserID:      userID,
+			PublishedAt: publishAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.CreateChirpPost(r.Context(), userID); err != nil {
+			return err
+		}
+		chirp = chirpResponse(dbChirp)
+
+		// Scheduled chirps are announced once published, by
+		// announceScheduledChirps.
+		if publishAt.Valid {
+			return q.CreateScheduledChirp(r.Context(), db.CreateScheduledChirpParams{ChirpID: chirp.ID, PublishAt: publishAt.Time})
+		}
+		return emitWebhookEvent(r.Context(), q, webhookEventChirpCreated, chirp.UserID, chirp)
 	})
+	if errors.Is(err, errChirpRateLimited) {
+		problem.Write(w, r, problemChirpRateLimited, "Chirp rate limit exceeded, try again later")
+		return
+	}
 	if err != nil {
-		log.Printf("error creating chirp: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not create chirp")
+		slog.ErrorContext(r.Context(), "creating chirp", "error", err)
+		respondWithDBError(w, r, err, "Could not create chirp")
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	}
+	cfg.metrics.ChirpCreated()
 
 	respondWithJSON(w, http.StatusCreated, chirp)
 }
 
-func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+// updateChirpHandler replaces the body of one of the caller's chirps. Editing
+// is an entitlement; moderators moderate by deleting instead.
+func (cfg *apiConfig) updateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID := principal.UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	var params requestBody
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	if len(params.Body) == 0 {
+		respondWithInvalidField(w, r, "body", problem.Required, "body is required")
+		return
+	}
+
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving chirp", "chirp_id", chirpID, "error", err)
+		respondWithDBError(w, r, err, "Could not update chirp")
+		return
+	}
+
+	if dbChirp.UserID != userID {
+		respondWithError(w, r, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	ents, _, err := cfg.entitlementsFor(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		slog.ErrorContext(r.Context(), "loading entitlements", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update chirp")
+		return
+	}
+
+	if !ents.Has(entitlements.FeatureEditChirps) {
+		problem.Write(w, r, problemPlanRequired, "Editing chirps is not available on your plan")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if len(params.Body) > ents.ChirpLength() {
+		respondWithInvalidField(w, r, "body", problem.TooLong, "Chirp is too long")
+		return
+	}
+
+	var chirp Chirp
+	err = cfg.tx.InTx(r.Context(), func(q db.Querier) error {
+		dbChirp, err := q.UpdateChirpBody(r.Context(), db.UpdateChirpBodyParams{
+			ID:   chirpID,
+			Body: sanitizeChirp(params.Body),
+		})
+		if err != nil {
+			return err
+		}
+		chirp = chirpResponse(dbChirp)
+		// Webhooks only hear about chirps that other users can see.
+		if !chirpVisible(dbChirp, uuid.Nil, time.Now().UTC()) {
+			return nil
+		}
+		return emitWebhookEvent(r.Context(), q, webhookEventChirpUpdated, chirp.UserID, chirp)
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		slog.ErrorContext(r.Context(), "updating chirp", "chirp_id", chirpID, "error", err)
+		respondWithDBError(w, r, err, "Could not update chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
+	userID := principal.UserID
 
 	chirpIDParam := r.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDParam)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
 		return
 	}
 
 	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "Chirp not found")
 			return
 		}
-		log.Printf("error retrieving chirp %s: %v", chirpID, err)
-		respondWithError(w, http.StatusInternalServerError, "Could not delete chirp")
+		slog.ErrorContext(r.Context(), "retrieving chirp", "chirp_id", chirpID, "error", err)
+		respondWithDBError(w, r, err, "Could not delete chirp")
 		return
 	}
 
-	if dbChirp.UserID != userID {
-		respondWithError(w, http.StatusForbidden, "Forbidden")
+	if dbChirp.UserID != userID && !principal.Role.AtLeast(auth.RoleModerator) {
+		respondWithError(w, r, http.StatusForbidden, "Forbidden")
 		return
 	}
 
-	if err := cfg.dbQueries.DeleteChirp(r.Context(), chirpID); err != nil {
-		log.Printf("error deleting chirp %s: %v", chirpID, err)
-		respondWithError(w, http.StatusInternalServerError, "Could not delete chirp")
+	err = cfg.tx.InTx(r.Context(), func(q db.Querier) error {
+		if err := q.DeleteChirp(r.Context(), chirpID); err != nil {
+			return err
+		}
+		if !chirpVisible(dbChirp, uuid.Nil, time.Now().UTC()) {
+			return nil
+		}
+		return emitWebhookEvent(r.Context(), q, webhookEventChirpDeleted, dbChirp.UserID, chirpResponse(dbChirp))
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "deleting chirp", "chirp_id", chirpID, "error", err)
+		respondWithDBError(w, r, err, "Could not delete chirp")
 		return
 	}
 
@@ -249,7 +573,7 @@ func (cfg *apiConfig) listChirpsHandler(w http.ResponseWriter, r *http.Request)
 		case "asc", "desc":
 			sortOrder = sortParam
 		default:
-			respondWithError(w, http.StatusBadRequest, "Invalid sort value")
+			respondWithInvalidField(w, r, "sort", problem.Invalid, "Invalid sort value")
 			return
 		}
 	}
@@ -264,27 +588,30 @@ func (cfg *apiConfig) listChirpsHandler(w http.ResponseWriter, r *http.Request)
 	} else {
 		authorID, parseErr := uuid.Parse(authorIDParam)
 		if parseErr != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid author_id")
+			respondWithInvalidField(w, r, "author_id", problem.Invalid, "Invalid author_id")
 			return
 		}
 		dbChirps, err = cfg.dbQueries.ListChirpsByAuthor(r.Context(), authorID)
 	}
 
 	if err != nil {
-		log.Printf("error listing chirps: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not retrieve chirps")
+		slog.ErrorContext(r.Context(), "listing chirps", "error", err)
+		respondWithDBError(w, r, err, "Could not retrieve chirps")
 		return
 	}
 
+	var viewer uuid.UUID
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		viewer = principal.UserID
+	}
+	now := time.Now().UTC()
+
 	chirps := make([]Chirp, 0, len(dbChirps))
 	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		})
+		if !chirpVisible(dbChirp, viewer, now) {
+			continue
+		}
+		chirps = append(chirps, chirpResponse(dbChirp))
 	}
 
 	sort.SliceStable(chirps, func(i, j int) bool {
@@ -307,88 +634,123 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 	chirpIDParam := r.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDParam)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid chirp ID")
 		return
 	}
 
 	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "Chirp not found")
 			return
 		}
-		log.Printf("error retrieving chirp %s: %v", chirpID, err)
-		respondWithError(w, http.StatusInternalServerError, "Could not retrieve chirp")
+		slog.ErrorContext(r.Context(), "retrieving chirp", "chirp_id", chirpID, "error", err)
+		respondWithDBError(w, r, err, "Could not retrieve chirp")
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	var viewer uuid.UUID
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		viewer = principal.UserID
+	}
+	if !chirpVisible(dbChirp, viewer, time.Now().UTC()) {
+		respondWithError(w, r, http.StatusNotFound, "Chirp not found")
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, chirp)
+	respondWithJSON(w, http.StatusOK, chirpResponse(dbChirp))
 }
 
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		UseCookies bool   `json:"use_cookies"`
 	}
 
 	var params requestBody
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
 	if params.Email == "" || params.Password == "" {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		problem.Write(w, r, problemInvalidCredentials, "")
 		return
 	}
 
-	dbUser, err := cfg.dbQueries.GetUserByEmail(r.Context(), params.Email)
+	throttles := cfg.loginThrottles(params.Email, clientIP(r))
+	outcome, err := cfg.verifyLogin(r.Context(), params.Email, params.Password, throttles)
 	if err != nil {
-		log.Printf("error retrieving user by email: %v", err)
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		slog.ErrorContext(r.Context(), "verifying login", "error", err)
+		respondWithDBError(w, r, err, "Could not log in")
 		return
 	}
 
-	match, err := auth.CheckPasswordHash(params.Password, dbUser.HashedPassword)
-	if err != nil {
-		log.Printf("error comparing password hash: %v", err)
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+	if !outcome.lockedUntil.IsZero() {
+		cfg.recordLoginAttempt(r, params.Email, uuid.NullUUID{}, loginFailureLocked)
+		retryAfter := int(time.Until(outcome.lockedUntil).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		problem.Write(w, r, problemLoginLocked, "Too many failed login attempts, try again later")
 		return
 	}
 
-	if !match {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+	if !outcome.ok {
+		cfg.recordLoginAttempt(r, params.Email, outcome.userID, loginFailureInvalidCredentials)
+		problem.Write(w, r, problemInvalidCredentials, "")
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
+	dbUser := outcome.user
+	cfg.upgradePasswordHash(r.Context(), dbUser, params.Password)
+	cfg.recordLoginAttempt(r, params.Email, outcome.userID, "")
+
+	user, err := cfg.userResponse(r.Context(), dbUser)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading user", "user_id", dbUser.ID, "error", err)
+		respondWithDBError(w, r, err, "Could not log in")
+		return
+	}
+
+	session, err := cfg.startSession(w, r.Context(), dbUser, params.UseCookies)
 	if err != nil {
-		log.Printf("error creating JWT: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not generate token")
+		slog.ErrorContext(r.Context(), "starting session", "user_id", dbUser.ID, "error", err)
+		respondWithDBError(w, r, err, "Could not generate token")
 		return
 	}
 
+	respondWithSession(w, user, session)
+}
+
+// loginSession holds the credentials issued by a successful login.
+type loginSession struct {
+	accessToken  string
+	refreshToken string
+	// csrfToken is only set for cookie sessions.
+	csrfToken string
+}
+
+// startSession issues an access token and a stored refresh token for dbUser.
+// Cookie sessions additionally get a CSRF token, and all three are written to
+// w as cookies.
+func (cfg *apiConfig) startSession(w http.ResponseWriter, ctx context.Context, dbUser db.User, useCookies bool) (loginSession, error) {
+	accessExpiresAt := time.Now().UTC().Add(time.Hour)
+	accessToken, err := auth.MakeJWTWithOptions(dbUser.ID, cfg.jwtSecret, time.Hour, auth.TokenOptions{
+		Role: auth.Role(dbUser.Role),
+	})
+	if err != nil {
+		return loginSession{}, fmt.Errorf("creating JWT: %w", err)
+	}
+
 	refreshExpiresAt := time.Now().UTC().Add(60 * 24 * time.Hour)
 
 	var refreshToken string
 	for i := 0; i < 5; i++ {
 		t, err := auth.MakeRefreshToken()
 		if err != nil {
-			log.Printf("error creating refresh token: %v", err)
-			respondWithError(w, http.StatusInternalServerError, "Could not generate token")
-			return
+			return loginSession{}, fmt.Errorf("creating refresh token: %w", err)
 		}
 
-		_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), db.CreateRefreshTokenParams{
+		_, err = cfg.dbQueries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
 			Token:     t,
 			UserID:    dbUser.ID,
 			ExpiresAt: refreshExpiresAt,
@@ -398,27 +760,52 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		if db.IsConflict(err, "refresh_tokens_pkey") {
 			continue
 		}
 
-		log.Printf("error storing refresh token: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not generate token")
-		return
+		return loginSession{}, fmt.Errorf("storing refresh token: %w", err)
 	}
 
 	if refreshToken == "" {
-		respondWithError(w, http.StatusInternalServerError, "Could not generate token")
-		return
+		return loginSession{}, errors.New("could not generate a unique refresh token")
 	}
 
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	session := loginSession{
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+	}
+
+	if useCookies {
+		session.csrfToken, err = auth.MakeCSRFToken()
+		if err != nil {
+			return loginSession{}, fmt.Errorf("creating CSRF token: %w", err)
+		}
+
+		auth.SetSessionCookies(w, cfg.sessionCookieOptions(), auth.SessionTokens{
+			AccessToken:      accessToken,
+			AccessExpiresAt:  accessExpiresAt,
+			RefreshToken:     refreshToken,
+			RefreshExpiresAt: refreshExpiresAt,
+			CSRFToken:        session.csrfToken,
+		})
+	}
+
+	return session, nil
+}
+
+// respondWithSession writes the login response for a new session. Cookie
+// sessions only receive the CSRF token, keeping credentials out of scripts.
+func respondWithSession(w http.ResponseWriter, user User, session loginSession) {
+	if session.csrfToken != "" {
+		respondWithJSON(w, http.StatusOK, struct {
+			User
+			CSRFToken string `json:"csrf_token"`
+		}{
+			User:      user,
+			CSRFToken: session.csrfToken,
+		})
+		return
 	}
 
 	response := struct {
@@ -427,45 +814,66 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		RefreshToken string `json:"refresh_token"`
 	}{
 		User:         user,
-		Token:        accessToken,
-		RefreshToken: refreshToken,
+		Token:        session.accessToken,
+		RefreshToken: session.refreshToken,
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
 func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
-	refreshToken, err := auth.GetBearerToken(r.Header)
+	refreshToken, fromCookie, err := auth.GetRefreshToken(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
+	if fromCookie {
+		if err := auth.CheckCSRF(r); err != nil {
+			problem.Write(w, r, auth.ProblemInvalidCSRF, "")
+			return
+		}
+	}
+
 	row, err := cfg.dbQueries.GetUserFromRefreshToken(r.Context(), refreshToken)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
-		log.Printf("error retrieving refresh token: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not refresh token")
+		slog.ErrorContext(r.Context(), "retrieving refresh token", "error", err)
+		respondWithDBError(w, r, err, "Could not refresh token")
 		return
 	}
 
-	if row.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	// OAuth clients refresh through POST /oauth/token, which keeps their scopes.
+	if row.RevokedAt.Valid || row.ClientID.Valid {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	if time.Now().UTC().After(row.ExpiresAt) {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(row.UserID, cfg.jwtSecret, time.Hour)
+	accessExpiresAt := time.Now().UTC().Add(time.Hour)
+	accessToken, err := auth.MakeJWTWithOptions(row.UserID, cfg.jwtSecret, time.Hour, auth.TokenOptions{
+		Role: auth.Role(row.UserRole),
+	})
 	if err != nil {
-		log.Printf("error creating JWT: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not refresh token")
+		slog.ErrorContext(r.Context(), "creating JWT", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not refresh token")
+		return
+	}
+	cfg.metrics.TokenRefreshed("session")
+
+	if fromCookie {
+		auth.SetSessionCookies(w, cfg.sessionCookieOptions(), auth.SessionTokens{
+			AccessToken:     accessToken,
+			AccessExpiresAt: accessExpiresAt,
+		})
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
@@ -473,71 +881,38 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
-	refreshToken, err := auth.GetBearerToken(r.Header)
+	refreshToken, fromCookie, err := auth.GetRefreshToken(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	_, err = cfg.dbQueries.GetRefreshToken(r.Context(), refreshToken)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	if fromCookie {
+		if err := auth.CheckCSRF(r); err != nil {
+			problem.Write(w, r, auth.ProblemInvalidCSRF, "")
 			return
 		}
-		log.Printf("error retrieving refresh token: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not revoke token")
-		return
-	}
-
-	if err := cfg.dbQueries.RevokeRefreshToken(r.Context(), refreshToken); err != nil {
-		log.Printf("error revoking refresh token: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not revoke token")
-		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
-
-func (cfg *apiConfig) polkaWebhookHandler(w http.ResponseWriter, r *http.Request) {
-	type webhookRequest struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
-	}
-
-	apiKey, err := auth.GetAPIKey(r.Header)
+	_, err = cfg.dbQueries.GetRefreshToken(r.Context(), refreshToken)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	if apiKey != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving refresh token", "error", err)
+		respondWithDBError(w, r, err, "Could not revoke token")
 		return
 	}
 
-	var req webhookRequest
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if err := cfg.dbQueries.RevokeRefreshToken(r.Context(), refreshToken); err != nil {
+		slog.ErrorContext(r.Context(), "revoking refresh token", "error", err)
+		respondWithDBError(w, r, err, "Could not revoke token")
 		return
 	}
 
-	if req.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-
-	if _, err := cfg.dbQueries.UpgradeToChirpyRed(r.Context(), req.Data.UserID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusNotFound, "User not found")
-			return
-		}
-		log.Printf("error upgrading user %s to chirpy red: %v", req.Data.UserID, err)
-		respondWithError(w, http.StatusInternalServerError, "Could not upgrade user")
-		return
+	if fromCookie {
+		auth.ClearSessionCookies(w, cfg.sessionCookieOptions())
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -550,26 +925,29 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var params requestBody
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
 	if params.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required")
+		respondWithInvalidField(w, r, "email", problem.Required, "Email is required")
 		return
 	}
 
 	if params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Password is required")
+		respondWithInvalidField(w, r, "password", problem.Required, "Password is required")
+		return
+	}
+
+	if err := cfg.passwordPolicy.Check(params.Password, params.Email); err != nil {
+		respondWithPasswordPolicyError(w, r, err)
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
-		log.Printf("error hashing password: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not create user")
+		slog.ErrorContext(r.Context(), "hashing password", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not create user")
 		return
 	}
 
@@ -578,22 +956,21 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hashedPassword,
 	})
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-			respondWithError(w, http.StatusBadRequest, "Email already exists")
+		if db.IsConflict(err, "users_email_key") {
+			problem.Write(w, r, problemEmailTaken, "")
 			return
 		}
-		log.Printf("error creating user: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not create user")
+		slog.ErrorContext(r.Context(), "creating user", "error", err)
+		respondWithDBError(w, r, err, "Could not create user")
 		return
 	}
 
 	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+		ID:        dbUser.ID,
+		CreatedAt: dbUser.CreatedAt,
+		UpdatedAt: dbUser.UpdatedAt,
+		Email:     dbUser.Email,
+		Role:      dbUser.Role,
 	}
 
 	respondWithJSON(w, http.StatusCreated, user)
@@ -605,39 +982,37 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		Password string `json:"password"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
+	userID := principal.UserID
 
 	var params requestBody
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
 	if params.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required")
+		respondWithInvalidField(w, r, "email", problem.Required, "Email is required")
 		return
 	}
 
 	if params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Password is required")
+		respondWithInvalidField(w, r, "password", problem.Required, "Password is required")
+		return
+	}
+
+	if err := cfg.passwordPolicy.Check(params.Password, params.Email); err != nil {
+		respondWithPasswordPolicyError(w, r, err)
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
-		log.Printf("error hashing password: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Could not update user")
+		slog.ErrorContext(r.Context(), "hashing password", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not update user")
 		return
 	}
 
@@ -647,28 +1022,28 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		HashedPassword: hashedPassword,
 	})
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-			respondWithError(w, http.StatusBadRequest, "Email already exists")
+		if db.IsConflict(err, "users_email_key") {
+			problem.Write(w, r, problemEmailTaken, "")
 			return
 		}
-		log.Printf("error updating user %s: %v", userID, err)
-		respondWithError(w, http.StatusInternalServerError, "Could not update user")
+		slog.ErrorContext(r.Context(), "updating user", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update user")
 		return
 	}
 
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	user, err := cfg.userResponse(r.Context(), dbUser)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading user", "user_id", userID, "error", err)
+		respondWithDBError(w, r, err, "Could not update user")
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, user)
 }
 
-func readinessHandler(w http.ResponseWriter, r *http.Request) {
+// livenessHandler reports that the process is serving requests. It checks
+// no dependencies, so an outage elsewhere does not get the server restarted.
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
@@ -682,80 +1057,230 @@ func assetsIndexHandler(w http.ResponseWriter, r *http.Request) {
 </pre></body></html>`))
 }
 
+// migrateOnStartup applies pending migrations when auto is set. Otherwise it
+// only warns, since serving with an outdated schema fails on the first query
+// that touches the new tables.
+func migrateOnStartup(ctx context.Context, dbConn *storage.DB, auto bool) error {
+	provider, err := migrate.NewProvider(dbConn)
+	if err != nil {
+		return err
+	}
+
+	if !auto {
+		pending, err := provider.HasPending(ctx)
+		if err != nil {
+			return fmt.Errorf("checking migrations: %w", err)
+		}
+		if pending {
+			slog.Warn("database has pending migrations; run \"chirpy migrate up\" or set DB_AUTO_MIGRATE=true")
+		}
+		return nil
+	}
+
+	results, err := provider.Up(ctx)
+	for _, r := range results {
+		slog.Info("applied migration", "version", r.Source.Version, "duration_ms", r.Duration.Milliseconds())
+	}
+	if err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	return nil
+}
+
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("warning: could not load .env file: %v", err)
 	}
 
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL environment variable not set")
+	inv, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg := inv.Config
+
+	if inv.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	dbConn, err := sql.Open("postgres", dbURL)
+	if err := auth.SetPasswordParams(cfg.Auth.Argon2.PasswordParams()); err != nil {
+		log.Fatalf("invalid Argon2id parameters: %v", err)
+	}
+
+	if len(inv.Args) > 0 {
+		if err := runCommand(cfg, inv.Args[0], inv.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if err := runServer(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// routes registers every endpoint on a new ServeMux.
+func (cfg *apiConfig) routes() *http.ServeMux {
+	authn := auth.NewAuthenticator(cfg.jwtSecret, cfg.loadPrincipal)
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/healthz", livenessHandler)
+	mux.Handle("GET /api/readyz", cfg.readiness)
+	mux.Handle("GET /metrics", cfg.metrics.Handler())
+	fileServer := http.FileServer(http.Dir("."))
+	appHandler := http.StripPrefix("/app", fileServer)
+	mux.Handle("/app", cfg.middlewareMetricsInc(appHandler))
+	mux.Handle("/app/", cfg.middlewareMetricsInc(appHandler))
+	mux.Handle("/app/assets", cfg.middlewareMetricsInc(http.HandlerFunc(assetsIndexHandler)))
+	adminOnly := func(h http.HandlerFunc) http.Handler {
+		return authn.Required(authn.RequireRole(auth.RoleAdmin)(h))
+	}
+	mux.Handle("GET /admin/metrics", adminOnly(cfg.adminMetricsHandler))
+	mux.Handle("POST /admin/reset", adminOnly(cfg.resetHandler))
+	mux.Handle("PUT /admin/users/{userID}/role", adminOnly(cfg.setUserRoleHandler))
+	mux.Handle("GET /admin/users/{userID}/entitlements", adminOnly(cfg.getUserEntitlementsHandler))
+	mux.Handle("PUT /admin/users/{userID}/entitlements/{feature}", adminOnly(cfg.setUserEntitlementHandler))
+	mux.Handle("DELETE /admin/users/{userID}/entitlements/{feature}", adminOnly(cfg.deleteUserEntitlementHandler))
+	mux.Handle("GET /admin/webhooks/events", adminOnly(cfg.listWebhookEventsHandler))
+	mux.Handle("POST /admin/webhooks/events/{source}/{eventID}/replay", adminOnly(cfg.replayWebhookEventHandler))
+	mux.HandleFunc("POST /api/users", cfg.createUserHandler)
+	accountOnly := func(h http.HandlerFunc) http.Handler {
+		return authn.Required(authn.RequireScopes(auth.ScopeAccount)(h))
+	}
+	chirpsWrite := func(h http.HandlerFunc) http.Handler {
+		return authn.Required(authn.RequireScopes(auth.ScopeChirpsWrite)(h))
+	}
+	mux.Handle("PUT /api/users", accountOnly(cfg.updateUserHandler))
+	mux.Handle("GET /api/entitlements", authn.Required(http.HandlerFunc(cfg.myEntitlementsHandler)))
+	mux.HandleFunc("POST /api/login", cfg.loginHandler)
+	mux.Handle("GET /api/auth/oidc/{provider}/login", authn.Optional(http.HandlerFunc(cfg.oidcLoginHandler)))
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/callback", cfg.oidcCallbackHandler)
+	mux.HandleFunc("POST /api/refresh", cfg.refreshHandler)
+	mux.HandleFunc("POST /api/revoke", cfg.revokeHandler)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.polkaWebhookHandler)
+	mux.Handle("GET /api/chirps", authn.Optional(http.HandlerFunc(cfg.listChirpsHandler)))
+	mux.Handle("GET /api/chirps/{chirpID}", authn.Optional(http.HandlerFunc(cfg.getChirpHandler)))
+	mux.Handle("POST /api/chirps", chirpsWrite(cfg.createChirpHandler))
+	mux.Handle("PUT /api/chirps/{chirpID}", chirpsWrite(cfg.updateChirpHandler))
+	mux.Handle("DELETE /api/chirps/{chirpID}", chirpsWrite(cfg.deleteChirpHandler))
+	mux.Handle("POST /api/oauth/clients", accountOnly(cfg.createOAuthClientHandler))
+	mux.Handle("GET /api/oauth/clients", accountOnly(cfg.listOAuthClientsHandler))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", accountOnly(cfg.deleteOAuthClientHandler))
+	mux.Handle("GET /oauth/authorize", authn.Optional(http.HandlerFunc(cfg.authorizeHandler)))
+	mux.Handle("POST /oauth/authorize", accountOnly(cfg.authorizeDecisionHandler))
+	mux.HandleFunc("POST /oauth/token", cfg.tokenHandler)
+	mux.HandleFunc("POST /oauth/introspect", cfg.introspectHandler)
+	mux.HandleFunc("POST /oauth/revoke", cfg.oauthRevokeHandler)
+	mux.Handle("POST /api/webhooks", accountOnly(cfg.createWebhookEndpointHandler))
+	mux.Handle("GET /api/webhooks", accountOnly(cfg.listWebhookEndpointsHandler))
+	mux.Handle("DELETE /api/webhooks/{endpointID}", accountOnly(cfg.deleteWebhookEndpointHandler))
+	mux.Handle("GET /api/webhooks/{endpointID}/deliveries", accountOnly(cfg.listWebhookDeliveriesHandler))
+	mux.Handle("POST /api/webhooks/deliveries/{deliveryID}/redeliver", accountOnly(cfg.redeliverWebhookHandler))
+
+	return mux
+}
+
+// runServer serves the API until SIGINT or SIGTERM, then drains in-flight
+// requests and background workers before closing the database.
+func runServer(cfg *config.Config) error {
+	logger := logging.New(os.Stdout, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "chirpy",
+		Exporter:    cfg.Tracing.Exporter,
+	})
 	if err != nil {
-		log.Fatalf("error opening database: %v", err)
+		return err
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("shutting down tracing", "error", err)
+		}
+	}()
 
-	if err := dbConn.Ping(); err != nil {
-		log.Fatalf("error pinging database: %v", err)
+	dbConn, err := storage.Open(cfg.Database.URL.Value(), cfg.Database.Pool())
+	if err != nil {
+		return err
 	}
 
 	defer func() {
 		if err := dbConn.Close(); err != nil {
-			log.Printf("error closing database: %v", err)
+			slog.Error("closing database", "error", err)
 		}
 	}()
 
-	dbQueries := db.New(dbConn)
-	platform := os.Getenv("PLATFORM")
-	if platform == "" {
-		platform = "dev"
+	if err := migrateOnStartup(context.Background(), dbConn, cfg.Database.AutoMigrate); err != nil {
+		return err
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable not set")
+	dbQueries := dbConn.Queries(tracing.WrapDB(dbConn, string(dbConn.Backend)))
+	if cfg.Polka.WebhookSecret == "" {
+		slog.Warn("POLKA_WEBHOOK_SECRET not set; Polka webhooks are authenticated with the static POLKA_KEY")
 	}
 
-	polkaKey := os.Getenv("POLKA_KEY")
-	if polkaKey == "" {
-		log.Fatal("POLKA_KEY environment variable not set")
+	discoverCtx, cancelDiscover := context.WithTimeout(context.Background(), 30*time.Second)
+	oidcProviders, err := discoverOIDCProviders(discoverCtx, cfg.OIDC)
+	cancelDiscover()
+	if err != nil {
+		return err
 	}
 
-	mux := http.NewServeMux()
 	apiCfg := &apiConfig{
-		dbQueries: dbQueries,
-		platform:  platform,
-		jwtSecret: jwtSecret,
-		polkaKey:  polkaKey,
+		dbQueries:          dbQueries,
+		tx:                 dbConn,
+		platform:           cfg.Platform,
+		jwtSecret:          cfg.Auth.JWTSecret.Value(),
+		polkaKey:           cfg.Polka.Key.Value(),
+		polkaWebhookSecret: []byte(cfg.Polka.WebhookSecret.Value()),
+		accountLockout:     cfg.Auth.Lockout.Account.Policy(),
+		ipLockout:          cfg.Auth.Lockout.IP.Policy(),
+		passwordPolicy:     cfg.Auth.Password.Policy(),
+		oidcProviders:      oidcProviders,
+		metrics:            metrics.New(dbConn.DB),
+	}
+	apiCfg.readiness, err = apiCfg.newReadiness(dbConn)
+	if err != nil {
+		return err
 	}
 
-	mux.HandleFunc("GET /api/healthz", readinessHandler)
-	fileServer := http.FileServer(http.Dir("."))
-	appHandler := http.StripPrefix("/app", fileServer)
-	mux.Handle("/app", apiCfg.middlewareMetricsInc(appHandler))
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(appHandler))
-	mux.Handle("/app/assets", apiCfg.middlewareMetricsInc(http.HandlerFunc(assetsIndexHandler)))
-	mux.HandleFunc("GET /admin/metrics", apiCfg.adminMetricsHandler)
-	mux.HandleFunc("POST /admin/reset", apiCfg.resetHandler)
-	mux.HandleFunc("POST /api/users", apiCfg.createUserHandler)
-	mux.HandleFunc("PUT /api/users", apiCfg.updateUserHandler)
-	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)
-	mux.HandleFunc("POST /api/refresh", apiCfg.refreshHandler)
-	mux.HandleFunc("POST /api/revoke", apiCfg.revokeHandler)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaWebhookHandler)
-	mux.HandleFunc("GET /api/chirps", apiCfg.listChirpsHandler)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpHandler)
-	mux.HandleFunc("POST /api/chirps", apiCfg.createChirpHandler)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpHandler)
-
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
-	}
-
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("server error: %v", err)
-	}
+	mux := apiCfg.routes()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	// Local development may point webhooks at localhost.
+	webhookClient := webhook.NewClient(webhookRequestTimeout, cfg.Platform == config.PlatformDev)
+	workers.Go(func() { apiCfg.runWebhookDeliveries(ctx, webhookClient) })
+
+	server := newServer(cfg.Server,
+		tracing.Middleware(logging.Middleware(logger, apiCfg.metrics.Middleware(tracing.NameByRoute(mux)))),
+	)
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	err = serve(ctx, server, ln, shutdownOptions{
+		Drain: func() {
+			// Restore default signal handling so a second signal exits
+			// immediately.
+			stop()
+			apiCfg.readiness.Drain()
+		},
+		Delay:   cfg.Server.ShutdownDelay.Std(),
+		Timeout: cfg.Server.ShutdownTimeout.Std(),
+	})
+
+	stop()
+	workers.Wait()
+	return err
 }
@@ -13,7 +13,7 @@ POLKA_KEY=$(grep -E '^POLKA_KEY=' .env | cut -d= -f2- | tr -d '"')
 
 printf '\n-- valid --\n'
 EMAIL="manual-$(date +%s)@example.com"
-PASSWORD="Sup3rSecret!"
+PASSWORD="Sup3rSecret!-orbit-42"
 USER_RAW=$(curl -s -w '\n%{http_code}' -X POST http://localhost:8080/api/users \
   -H 'Content-Type: application/json' \
   -d "{\"email\":\"$EMAIL\",\"password\":\"$PASSWORD\"}")
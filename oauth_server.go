@@ -0,0 +1,801 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"chirpy/internal/auth"
+	db "chirpy/internal/database"
+	"chirpy/internal/problem"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeTTL         = 10 * time.Minute
+	oauthAccessTokenTTL  = time.Hour
+	oauthRefreshTokenTTL = 60 * 24 * time.Hour
+)
+
+// OAuthClient is the JSON representation of a registered third-party client.
+// ClientSecret is only returned once, when a confidential client is created.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+func oauthClientFromDB(c db.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       c.Scopes,
+		Confidential: c.SecretHash.Valid,
+	}
+}
+
+// createOAuthClientHandler registers a third-party client owned by the caller.
+// Confidential clients receive a secret; public clients (mobile and
+// single-page apps) rely on PKCE alone.
+func (cfg *apiConfig) createOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	type createOAuthClientRequest struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req createOAuthClientRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		respondWithInvalidField(w, r, "name", problem.Invalid, "Name must be between 1 and 100 characters")
+		return
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		respondWithInvalidField(w, r, "redirect_uris", problem.Required, "At least one redirect URI is required")
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if !auth.ValidRedirectURI(uri) {
+			respondWithInvalidField(w, r, "redirect_uris", problem.Invalid, fmt.Sprintf("Invalid redirect URI %q", uri))
+			return
+		}
+	}
+
+	scopes, err := auth.ParseOAuthScopes(strings.Join(req.Scopes, " "))
+	if err != nil || len(scopes) == 0 {
+		respondWithInvalidField(w, r, "scopes", problem.Invalid, "Scopes must be a non-empty list of supported scopes")
+		return
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if req.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "creating client secret", "error", err)
+			respondWithError(w, r, http.StatusInternalServerError, "Could not create client")
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.dbQueries.CreateOAuthClient(r.Context(), db.CreateOAuthClientParams{
+		OwnerID:      principal.UserID,
+		Name:         req.Name,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "creating oauth client", "error", err)
+		respondWithDBError(w, r, err, "Could not create client")
+		return
+	}
+
+	response := oauthClientFromDB(client)
+	response.ClientSecret = secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) listOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	clients, err := cfg.dbQueries.ListOAuthClientsByOwner(r.Context(), principal.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "listing oauth clients", "error", err)
+		respondWithDBError(w, r, err, "Could not list clients")
+		return
+	}
+
+	response := make([]OAuthClient, 0, len(clients))
+	for _, c := range clients {
+		response = append(response, oauthClientFromDB(c))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// deleteOAuthClientHandler removes a client. Its authorization codes and
+// refresh tokens are deleted with it, and loadPrincipal rejects its
+// outstanding access tokens.
+func (cfg *apiConfig) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteOAuthClient(r.Context(), db.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: principal.UserID,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "deleting oauth client", "error", err)
+		respondWithDBError(w, r, err, "Could not delete client")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, r, http.StatusNotFound, "Client not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest is a validated RFC 6749 authorization request.
+type authorizationRequest struct {
+	client        db.OauthClient
+	redirectURI   string
+	scopes        []string
+	state         string
+	codeChallenge string
+}
+
+// authorizationError is an error reported back to the client through its
+// redirect URI rather than shown to the user.
+type authorizationError struct {
+	code        string
+	description string
+}
+
+func (e *authorizationError) Error() string { return e.code + ": " + e.description }
+
+// errInvalidAuthorizationClient means the client or redirect URI could not be
+// verified, so the user must not be redirected anywhere.
+var errInvalidAuthorizationClient = errors.New("unknown client or unregistered redirect URI")
+
+// parseAuthorizationRequest validates the parameters shared by the consent
+// page and the consent form submission.
+func (cfg *apiConfig) parseAuthorizationRequest(ctx context.Context, params url.Values) (*authorizationRequest, error) {
+	clientID, err := uuid.Parse(params.Get("client_id"))
+	if err != nil {
+		return nil, errInvalidAuthorizationClient
+	}
+
+	client, err := cfg.dbQueries.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, errInvalidAuthorizationClient
+		}
+		return nil, err
+	}
+
+	redirectURI := params.Get("redirect_uri")
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		return nil, errInvalidAuthorizationClient
+	}
+
+	req := &authorizationRequest{
+		client:        client,
+		redirectURI:   redirectURI,
+		state:         params.Get("state"),
+		codeChallenge: params.Get("code_challenge"),
+	}
+
+	if params.Get("response_type") != "code" {
+		return req, &authorizationError{"unsupported_response_type", "only the code response type is supported"}
+	}
+	if req.codeChallenge == "" || params.Get("code_challenge_method") != "S256" {
+		return req, &authorizationError{"invalid_request", "PKCE with code_challenge_method S256 is required"}
+	}
+
+	scope := params.Get("scope")
+	if scope == "" {
+		scope = strings.Join(client.Scopes, " ")
+	}
+	req.scopes, err = auth.ParseOAuthScopes(scope)
+	if err != nil || len(req.scopes) == 0 || !auth.ScopesSubset(req.scopes, client.Scopes) {
+		return req, &authorizationError{"invalid_scope", "the requested scope is not allowed for this client"}
+	}
+
+	return req, nil
+}
+
+// redirect sends the user back to the client's redirect URI with
+// the given query parameters and the original state.
+func (req *authorizationRequest) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+
+	target, _ := url.Parse(req.redirectURI)
+	query := target.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// respondToAuthorizationError redirects client-correctable errors back to
+// the client and shows the rest to the user.
+func respondToAuthorizationError(w http.ResponseWriter, r *http.Request, req *authorizationRequest, err error) {
+	var authErr *authorizationError
+	switch {
+	case errors.As(err, &authErr):
+		req.redirect(w, r, url.Values{
+			"error":             {authErr.code},
+			"error_description": {authErr.description},
+		})
+	case errors.Is(err, errInvalidAuthorizationClient):
+		respondWithError(w, r, http.StatusBadRequest, "Unknown client or unregistered redirect URI")
+	default:
+		slog.ErrorContext(r.Context(), "validating authorization request", "error", err)
+		respondWithDBError(w, r, err, "Could not process authorization request")
+	}
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Authorize {{.ClientName}} - Chirpy</title>
+  </head>
+  <body>
+    <h1>Authorize {{.ClientName}}</h1>
+    <p>{{.ClientName}} would like to access your Chirpy account. It will be able to:</p>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>
+      {{end}}
+    </ul>
+    <p>You will be redirected to {{.RedirectURI}}.</p>
+    <form method="post" action="/oauth/authorize">
+      {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+      {{end}}
+      <button type="submit" name="decision" value="approve">Allow</button>
+      <button type="submit" name="decision" value="deny">Deny</button>
+    </form>
+  </body>
+</html>
+`))
+
+var signInTemplate = template.Must(template.New("sign-in").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Sign in - Chirpy</title>
+  </head>
+  <body>
+    <h1>Sign in to continue</h1>
+    <p>{{.ClientName}} would like to access your Chirpy account.</p>
+    <p><a href="/app/">Sign in to Chirpy</a>, then <a href="{{.ContinueURL}}">continue</a>.</p>
+  </body>
+</html>
+`))
+
+// authorizeHandler shows the signed-in user a consent page for an
+// authorization-code request. Session cookies are SameSite=Strict, so they
+// are not sent on the redirect from the client's site; anonymous visitors get
+// a page linking back to the same URL, which the browser then treats as a
+// same-site navigation.
+func (cfg *apiConfig) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := cfg.parseAuthorizationRequest(r.Context(), r.URL.Query())
+	if err != nil {
+		respondToAuthorizationError(w, r, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// Refuse framing so another site cannot trick the user into clicking Allow.
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		if err := signInTemplate.Execute(w, struct {
+			ClientName  string
+			ContinueURL string
+		}{
+			ClientName:  req.client.Name,
+			ContinueURL: r.URL.RequestURI(),
+		}); err != nil {
+			slog.ErrorContext(r.Context(), "rendering sign-in page", "error", err)
+		}
+		return
+	}
+	if !principal.HasScope(auth.ScopeAccount) {
+		respondWithError(w, r, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	scopes := make([]string, 0, len(req.scopes))
+	for _, s := range req.scopes {
+		scopes = append(scopes, auth.OAuthScopes[s])
+	}
+
+	params := map[string]string{
+		"response_type":         "code",
+		"client_id":             req.client.ID.String(),
+		"redirect_uri":          req.redirectURI,
+		"scope":                 strings.Join(req.scopes, " "),
+		"state":                 req.state,
+		"code_challenge":        req.codeChallenge,
+		"code_challenge_method": "S256",
+	}
+	if cookie, err := r.Cookie(auth.CSRFCookie); err == nil {
+		params[auth.CSRFFormField] = cookie.Value
+	}
+
+	if err := consentTemplate.Execute(w, struct {
+		ClientName  string
+		RedirectURI string
+		Scopes      []string
+		Params      map[string]string
+	}{
+		ClientName:  req.client.Name,
+		RedirectURI: req.redirectURI,
+		Scopes:      scopes,
+		Params:      params,
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "rendering consent page", "error", err)
+	}
+}
+
+// authorizeDecisionHandler handles the consent form. On approval it issues a
+// single-use authorization code bound to the client, redirect URI, scopes and
+// PKCE challenge.
+func (cfg *apiConfig) authorizeDecisionHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid form")
+		return
+	}
+
+	req, err := cfg.parseAuthorizationRequest(r.Context(), r.PostForm)
+	if err != nil {
+		respondToAuthorizationError(w, r, req, err)
+		return
+	}
+
+	if r.PostForm.Get("decision") != "approve" {
+		req.redirect(w, r, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"the user denied the request"},
+		})
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "creating authorization code", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not authorize client")
+		return
+	}
+
+	if err := cfg.dbQueries.CreateOAuthAuthorizationCode(r.Context(), db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        principal.UserID,
+		RedirectUri:   req.redirectURI,
+		Scope:         strings.Join(req.scopes, " "),
+		CodeChallenge: req.codeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(oauthCodeTTL),
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "storing authorization code", "error", err)
+		respondWithDBError(w, r, err, "Could not authorize client")
+		return
+	}
+
+	if err := cfg.dbQueries.DeleteExpiredOAuthAuthorizationCodes(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "deleting expired authorization codes", "error", err)
+	}
+
+	req.redirect(w, r, url.Values{"code": {code}})
+}
+
+// oauthError is an RFC 6749 section 5.2 error response.
+type oauthError struct {
+	status      int
+	code        string
+	description string
+}
+
+func respondWithOAuthError(w http.ResponseWriter, e oauthError) {
+	w.Header().Set("Cache-Control", "no-store")
+	if e.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	respondWithJSON(w, e.status, map[string]string{
+		"error":             e.code,
+		"error_description": e.description,
+	})
+}
+
+var errInvalidClient = oauthError{http.StatusUnauthorized, "invalid_client", "client authentication failed"}
+
+// authenticateOAuthClient identifies the client making a token, introspection
+// or revocation request from HTTP Basic credentials or the client_id and
+// client_secret form fields. Confidential clients must present their secret.
+func (cfg *apiConfig) authenticateOAuthClient(r *http.Request) (db.OauthClient, *oauthError) {
+	rawID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes Basic credentials.
+		var idErr, secretErr error
+		rawID, idErr = url.QueryUnescape(rawID)
+		secret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			return db.OauthClient{}, &errInvalidClient
+		}
+	} else {
+		rawID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(rawID)
+	if err != nil {
+		return db.OauthClient{}, &errInvalidClient
+	}
+
+	client, err := cfg.dbQueries.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return db.OauthClient{}, &errInvalidClient
+		}
+		slog.ErrorContext(r.Context(), "retrieving oauth client", "error", err)
+		return db.OauthClient{}, &oauthError{http.StatusInternalServerError, "server_error", "could not authenticate client"}
+	}
+
+	if client.SecretHash.Valid && !auth.CheckTokenHash(secret, client.SecretHash.String) {
+		return db.OauthClient{}, &errInvalidClient
+	}
+	if !client.SecretHash.Valid && secret != "" {
+		return db.OauthClient{}, &errInvalidClient
+	}
+
+	return client, nil
+}
+
+// tokenHandler implements the RFC 6749 token endpoint for the
+// authorization_code (with PKCE) and refresh_token grants.
+func (cfg *apiConfig) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, oauthError{http.StatusBadRequest, "invalid_request", "malformed form body"})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		respondWithOAuthError(w, *oauthErr)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		cfg.exchangeOAuthRefreshToken(w, r, client)
+	default:
+		respondWithOAuthError(w, oauthError{http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token"})
+	}
+}
+
+var errInvalidGrant = oauthError{http.StatusBadRequest, "invalid_grant", "the authorization grant is invalid, expired or revoked"}
+
+func (cfg *apiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client db.OauthClient) {
+	code, err := cfg.dbQueries.ConsumeOAuthAuthorizationCode(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithOAuthError(w, errInvalidGrant)
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving authorization code", "error", err)
+		respondWithOAuthError(w, oauthError{http.StatusInternalServerError, "server_error", "could not issue token"})
+		return
+	}
+
+	if code.ClientID != client.ID ||
+		code.RedirectUri != r.PostForm.Get("redirect_uri") ||
+		time.Now().UTC().After(code.ExpiresAt) ||
+		!auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithOAuthError(w, errInvalidGrant)
+		return
+	}
+
+	cfg.issueOAuthTokens(w, r.Context(), client, code.UserID, strings.Fields(code.Scope), nil)
+}
+
+// errRefreshTokenRotated aborts rotating a refresh token that a concurrent
+// request has already rotated.
+var errRefreshTokenRotated = errors.New("refresh token already rotated")
+
+// exchangeOAuthRefreshToken rotates a client's refresh token: the presented
+// token is revoked and a new one issued, optionally with narrower scopes.
+func (cfg *apiConfig) exchangeOAuthRefreshToken(w http.ResponseWriter, r *http.Request, client db.OauthClient) {
+	refreshToken := r.PostForm.Get("refresh_token")
+	row, err := cfg.dbQueries.GetUserFromRefreshToken(r.Context(), refreshToken)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithOAuthError(w, errInvalidGrant)
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving refresh token", "error", err)
+		respondWithOAuthError(w, oauthError{http.StatusInternalServerError, "server_error", "could not issue token"})
+		return
+	}
+
+	if !row.ClientID.Valid || row.ClientID.UUID != client.ID || row.RevokedAt.Valid || time.Now().UTC().After(row.ExpiresAt) {
+		respondWithOAuthError(w, errInvalidGrant)
+		return
+	}
+
+	scopes := strings.Fields(row.Scope.String)
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		narrowed, err := auth.ParseOAuthScopes(requested)
+		if err != nil || !auth.ScopesSubset(narrowed, scopes) {
+			respondWithOAuthError(w, oauthError{http.StatusBadRequest, "invalid_scope", "the requested scope exceeds the original grant"})
+			return
+		}
+		scopes = narrowed
+	}
+
+	// The old token is revoked in the transaction that stores the new one, so
+	// a failure in between cannot leave the client without a grant.
+	issued := cfg.issueOAuthTokens(w, r.Context(), client, row.UserID, scopes, func(q db.Querier) error {
+		// Only one concurrent request may rotate a given refresh token.
+		revoked, err := q.RevokeActiveRefreshToken(r.Context(), refreshToken)
+		if err != nil {
+			return fmt.Errorf("revoking refresh token: %w", err)
+		}
+		if revoked == 0 {
+			return errRefreshTokenRotated
+		}
+		return nil
+	})
+	if issued {
+		cfg.metrics.TokenRefreshed("oauth")
+	}
+}
+
+// issueOAuthTokens writes a successful RFC 6749 section 5.1 token response
+// and reports whether it did. A non-nil before runs in the transaction that
+// stores the new refresh token, so its changes only stick if it is stored.
+func (cfg *apiConfig) issueOAuthTokens(w http.ResponseWriter, ctx context.Context, client db.OauthClient, userID uuid.UUID, scopes []string, before func(db.Querier) error) bool {
+	accessToken, err := auth.MakeJWTWithOptions(userID, cfg.jwtSecret, oauthAccessTokenTTL, auth.TokenOptions{
+		Scopes:   scopes,
+		ClientID: client.ID.String(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "creating JWT", "error", err)
+		respondWithOAuthError(w, oauthError{http.StatusInternalServerError, "server_error", "could not issue token"})
+		return false
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	var refreshToken string
+	for i := 0; i < 5; i++ {
+		t, err := auth.MakeRefreshToken()
+		if err != nil {
+			slog.ErrorContext(ctx, "creating refresh token", "error", err)
+			break
+		}
+
+		// A collision aborts the whole transaction, so before is run again
+		// with the next token.
+		err = cfg.tx.InTx(ctx, func(q db.Querier) error {
+			if before != nil {
+				if err := before(q); err != nil {
+					return err
+				}
+			}
+			_, err := q.CreateOAuthRefreshToken(ctx, db.CreateOAuthRefreshTokenParams{
+				Token:     t,
+				UserID:    userID,
+				ExpiresAt: time.Now().UTC().Add(oauthRefreshTokenTTL),
+				ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+				Scope:     sql.NullString{String: scope, Valid: true},
+			})
+			return err
+		})
+		if err == nil {
+			refreshToken = t
+			break
+		}
+
+		if errors.Is(err, errRefreshTokenRotated) {
+			respondWithOAuthError(w, errInvalidGrant)
+			return false
+		}
+		if db.IsConflict(err, "refresh_tokens_pkey") {
+			continue
+		}
+
+		slog.ErrorContext(ctx, "storing refresh token", "error", err)
+		break
+	}
+
+	if refreshToken == "" {
+		respondWithOAuthError(w, oauthError{http.StatusInternalServerError, "server_error", "could not issue token"})
+		return false
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	respondWithJSON(w, http.StatusOK, struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	})
+	return true
+}
+
+// introspectionResponse is an RFC 7662 token introspection response.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// introspectHandler implements RFC 7662. Clients may only introspect tokens
+// issued to themselves; anything else is reported as inactive.
+func (cfg *apiConfig) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, oauthError{http.StatusBadRequest, "invalid_request", "malformed form body"})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		respondWithOAuthError(w, *oauthErr)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if claims, err := auth.ParseJWT(token, cfg.jwtSecret); err == nil {
+		if claims.ClientID != client.ID.String() {
+			respondWithJSON(w, http.StatusOK, introspectionResponse{})
+			return
+		}
+		response := introspectionResponse{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   claims.Subject,
+			TokenType: "access_token",
+		}
+		if claims.ExpiresAt != nil {
+			response.ExpiresAt = claims.ExpiresAt.Unix()
+		}
+		if claims.IssuedAt != nil {
+			response.IssuedAt = claims.IssuedAt.Unix()
+		}
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	row, err := cfg.dbQueries.GetRefreshToken(r.Context(), token)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			slog.ErrorContext(r.Context(), "retrieving refresh token", "error", err)
+			respondWithOAuthError(w, oauthError{http.StatusInternalServerError, "server_error", "could not introspect token"})
+			return
+		}
+		respondWithJSON(w, http.StatusOK, introspectionResponse{})
+		return
+	}
+
+	if !row.ClientID.Valid || row.ClientID.UUID != client.ID || row.RevokedAt.Valid || time.Now().UTC().After(row.ExpiresAt) {
+		respondWithJSON(w, http.StatusOK, introspectionResponse{})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, introspectionResponse{
+		Active:    true,
+		Scope:     row.Scope.String,
+		ClientID:  row.ClientID.UUID.String(),
+		Subject:   row.UserID.String(),
+		TokenType: "refresh_token",
+		ExpiresAt: row.ExpiresAt.Unix(),
+		IssuedAt:  row.CreatedAt.Unix(),
+	})
+}
+
+// oauthRevokeHandler implements RFC 7009 for refresh tokens. Unknown tokens
+// and tokens belonging to other clients are ignored, as the RFC requires.
+// Access tokens are short-lived JWTs and expire on their own.
+func (cfg *apiConfig) oauthRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, oauthError{http.StatusBadRequest, "invalid_request", "malformed form body"})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		respondWithOAuthError(w, *oauthErr)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	row, err := cfg.dbQueries.GetRefreshToken(r.Context(), token)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+	case err != nil:
+		slog.ErrorContext(r.Context(), "retrieving refresh token", "error", err)
+		respondWithOAuthError(w, oauthError{http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token"})
+		return
+	case row.ClientID.Valid && row.ClientID.UUID == client.ID:
+		if err := cfg.dbQueries.RevokeRefreshToken(r.Context(), token); err != nil {
+			slog.ErrorContext(r.Context(), "revoking refresh token", "error", err)
+			respondWithOAuthError(w, oauthError{http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token"})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	db "chirpy/internal/database"
+	"chirpy/internal/oidc"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcLoginStateTTL = 10 * time.Minute
+	// oidcStateCookie binds a login to the browser that started it, so that a
+	// callback URL from someone else's login is rejected (RFC 6749 §10.12).
+	oidcStateCookieName = "chirpy_oidc_state"
+)
+
+var (
+	errIdentityLinkedElsewhere = errors.New("identity is linked to another account")
+	errIdentityEmailTaken      = errors.New("an account with this email already exists; sign in and link this provider from your account")
+	errIdentityMissingEmail    = errors.New("provider did not share an email address")
+)
+
+// discoverOIDCProviders discovers every configured login provider, keyed by
+// name.
+func discoverOIDCProviders(ctx context.Context, configs []config.OIDCProvider) (map[string]*oidc.Provider, error) {
+	providers := make(map[string]*oidc.Provider)
+
+	for _, c := range configs {
+		provider, err := oidc.Discover(ctx, c.OIDCConfig(), nil)
+		if err != nil {
+			return nil, err
+		}
+		providers[c.Name] = provider
+	}
+
+	return providers, nil
+}
+
+// oidcLoginHandler starts an authorization-code + PKCE login with an external
+// provider. When the caller is already signed in, the resulting identity is
+// linked to their account instead of signing in as someone else.
+func (cfg *apiConfig) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := cfg.oidcProviders[r.PathValue("provider")]
+	if !ok {
+		respondWithError(w, r, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+
+	// Only the user's own session may link an identity: one linked through a
+	// third-party client's token could be used to sign in as the user.
+	var linkUserID uuid.NullUUID
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		if !principal.HasScope(auth.ScopeAccount) {
+			respondWithError(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+		linkUserID = uuid.NullUUID{UUID: principal.UserID, Valid: true}
+	}
+
+	state, stateErr := oidc.RandomString()
+	nonce, nonceErr := oidc.RandomString()
+	verifier, verifierErr := oidc.RandomString()
+	if err := errors.Join(stateErr, nonceErr, verifierErr); err != nil {
+		slog.ErrorContext(r.Context(), "generating oidc login state", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not start login")
+		return
+	}
+
+	if err := cfg.dbQueries.CreateOIDCLoginState(r.Context(), db.CreateOIDCLoginStateParams{
+		State:        state,
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		UseCookies:   r.URL.Query().Get("use_cookies") == "true",
+		ExpiresAt:    time.Now().UTC().Add(oidcLoginStateTTL),
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "storing oidc login state", "error", err)
+		respondWithDBError(w, r, err, "Could not start login")
+		return
+	}
+
+	if err := cfg.dbQueries.DeleteExpiredOIDCLoginStates(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "deleting expired oidc login states", "error", err)
+	}
+
+	http.SetCookie(w, cfg.oidcStateCookie(provider.Name(), state, int(oidcLoginStateTTL.Seconds())))
+	http.Redirect(w, r, provider.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// oidcCallbackHandler completes an external login and issues normal Chirpy
+// tokens. Cookie sessions are redirected to the app; API clients receive the
+// same JSON body as POST /api/login.
+func (cfg *apiConfig) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	providerName := r.PathValue("provider")
+	provider, ok := cfg.oidcProviders[providerName]
+	if !ok {
+		respondWithError(w, r, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		respondWithError(w, r, http.StatusBadRequest, "Login was cancelled or denied")
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid or expired login state")
+		return
+	}
+	http.SetCookie(w, cfg.oidcStateCookie(providerName, "", -1))
+
+	loginState, err := cfg.dbQueries.ConsumeOIDCLoginState(r.Context(), state)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid or expired login state")
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving oidc login state", "error", err)
+		respondWithDBError(w, r, err, "Could not complete login")
+		return
+	}
+
+	if loginState.Provider != providerName || time.Now().UTC().After(loginState.ExpiresAt) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid or expired login state")
+		return
+	}
+
+	rawIDToken, err := provider.Exchange(r.Context(), query.Get("code"), loginState.CodeVerifier)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "exchanging authorization code", "provider", providerName, "error", err)
+		respondWithError(w, r, http.StatusUnauthorized, "Could not verify identity")
+		return
+	}
+
+	identity, err := provider.VerifyIDToken(r.Context(), rawIDToken, loginState.Nonce)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "verifying id token", "provider", providerName, "error", err)
+		respondWithError(w, r, http.StatusUnauthorized, "Could not verify identity")
+		return
+	}
+
+	dbUser, err := cfg.userForIdentity(r.Context(), providerName, identity, loginState.LinkUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errIdentityLinkedElsewhere), errors.Is(err, errIdentityEmailTaken):
+			respondWithError(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, errIdentityMissingEmail):
+			respondWithError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			slog.ErrorContext(r.Context(), "resolving identity", "provider", providerName, "error", err)
+			respondWithDBError(w, r, err, "Could not complete login")
+		}
+		return
+	}
+
+	user, err := cfg.userResponse(r.Context(), dbUser)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "loading user", "user_id", dbUser.ID, "error", err)
+		respondWithDBError(w, r, err, "Could not complete login")
+		return
+	}
+
+	session, err := cfg.startSession(w, r.Context(), dbUser, loginState.UseCookies)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "starting session", "user_id", dbUser.ID, "error", err)
+		respondWithDBError(w, r, err, "Could not generate token")
+		return
+	}
+	cfg.metrics.Login("oidc", "success")
+
+	if loginState.UseCookies {
+		http.Redirect(w, r, "/app/", http.StatusSeeOther)
+		return
+	}
+
+	respondWithSession(w, user, session)
+}
+
+// oidcStateCookie returns the cookie holding the state of a login with
+// provider, kept for maxAge seconds; a negative maxAge deletes it. It is scoped to the callback and
+// SameSite=Lax, so the browser sends it on the provider's redirect back.
+func (cfg *apiConfig) oidcStateCookie(provider, state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/api/auth/oidc/" + provider + "/callback",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cfg.sessionCookieOptions().Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// userForIdentity returns the account linked to an external identity, linking
+// it first if needed: to linkUserID when the login was started by a signed-in
+// user, otherwise to a new password-less account. An identity is never linked
+// to an existing account by email alone, since Chirpy does not verify the
+// emails accounts sign up with; that takes errIdentityEmailTaken, and the
+// owner links the provider from a signed-in session instead.
+func (cfg *apiConfig) userForIdentity(ctx context.Context, provider string, identity *oidc.Identity, linkUserID uuid.NullUUID) (db.User, error) {
+	existing, err := cfg.dbQueries.GetUserIdentity(ctx, db.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  identity.Subject,
+	})
+	if err == nil {
+		if linkUserID.Valid && existing.UserID != linkUserID.UUID {
+			return db.User{}, errIdentityLinkedElsewhere
+		}
+		return cfg.dbQueries.GetUser(ctx, existing.UserID)
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return db.User{}, err
+	}
+
+	var dbUser db.User
+	switch {
+	case linkUserID.Valid:
+		dbUser, err = cfg.dbQueries.GetUser(ctx, linkUserID.UUID)
+	case identity.Email == "":
+		return db.User{}, errIdentityMissingEmail
+	default:
+		dbUser, err = cfg.createExternalUser(ctx, identity.Email)
+	}
+	if err != nil {
+		return db.User{}, err
+	}
+
+	if _, err := cfg.dbQueries.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		Provider: provider,
+		Subject:  identity.Subject,
+		UserID:   dbUser.ID,
+		Email:    identity.Email,
+	}); err != nil {
+		return db.User{}, fmt.Errorf("linking identity: %w", err)
+	}
+
+	return dbUser, nil
+}
+
+// createExternalUser creates an account for a new external identity. The
+// password is random and never revealed, so the account can only sign in
+// through its linked provider until the user sets a password.
+func (cfg *apiConfig) createExternalUser(ctx context.Context, email string) (db.User, error) {
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return db.User{}, err
+	}
+
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	dbUser, err := cfg.dbQueries.CreateUser(ctx, db.CreateUserParams{
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if db.IsConflict(err, "users_email_key") {
+			return db.User{}, errIdentityEmailTaken
+		}
+		return db.User{}, err
+	}
+
+	return dbUser, nil
+}
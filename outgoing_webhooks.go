@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	db "chirpy/internal/database"
+	"chirpy/internal/problem"
+	"chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// Events delivered to registered webhook endpoints.
+const (
+	webhookEventChirpCreated   = "chirp.created"
+	webhookEventChirpUpdated   = "chirp.updated"
+	webhookEventChirpDeleted   = "chirp.deleted"
+	webhookEventUserUpgraded   = "user.upgraded"
+	webhookEventUserDowngraded = "user.downgraded"
+)
+
+var webhookEventTypes = []string{
+	webhookEventChirpCreated,
+	webhookEventChirpUpdated,
+	webhookEventChirpDeleted,
+	webhookEventUserUpgraded,
+	webhookEventUserDowngraded,
+}
+
+// Headers sent with every outgoing delivery. The signature covers the
+// timestamp and body, see package webhook.
+const (
+	webhookIDHeader        = "X-Chirpy-Webhook-Id"
+	webhookEventHeader     = "X-Chirpy-Event"
+	webhookTimestampHeader = "X-Chirpy-Timestamp"
+	webhookSignatureHeader = "X-Chirpy-Signature"
+)
+
+const (
+	webhookDeliveryStatusPending   = "pending"
+	webhookDeliveryStatusSucceeded = "succeeded"
+	webhookDeliveryStatusFailed    = "failed"
+)
+
+const (
+	webhookPollInterval   = 5 * time.Second
+	webhookBatchSize      = 20
+	webhookRequestTimeout = 10 * time.Second
+	// webhookLease keeps a claimed delivery from being picked up again while
+	// it is in flight. If the process dies, the delivery is retried after it.
+	webhookLease       = 5 * time.Minute
+	webhookMaxAttempts = 10
+)
+
+// webhookRetryDelay returns the backoff before retrying a delivery that has
+// failed attempts times: 30s doubling up to 6h.
+func webhookRetryDelay(attempts int) time.Duration {
+	const base, maxDelay = 30 * time.Second, 6 * time.Hour
+	if attempts < 1 {
+		return base
+	}
+	if attempts > 20 {
+		return maxDelay
+	}
+	return min(base<<(attempts-1), maxDelay)
+}
+
+// webhookPayload is the JSON body of every delivery.
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// emitWebhookEvent queues eventType for every endpoint subscribed to it that
+// belongs to userID, or that watches all users and is owned by an admin. It
+// queues through q, so that callers pass the transaction of the write that
+// caused the event and the two commit or roll back together.
+func emitWebhookEvent(ctx context.Context, q db.Querier, eventType string, userID uuid.UUID, data any) error {
+	eventID := uuid.New()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding webhook: %w", err)
+	}
+
+	if _, err := q.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   payload,
+		UserID:    userID,
+	}); err != nil {
+		return fmt.Errorf("queueing webhook: %w", err)
+	}
+	return nil
+}
+
+// announceScheduledChirps queues chirp.created for every scheduled chirp
+// whose publish time has passed. Each batch is claimed and queued in one
+// transaction, so every chirp is announced exactly once.
+func (cfg *apiConfig) announceScheduledChirps(ctx context.Context) {
+	for {
+		var claimed int
+		err := cfg.tx.InTx(ctx, func(q db.Querier) error {
+			ids, err := q.ClaimPublishedChirps(ctx, webhookBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(ids)
+			for _, id := range ids {
+				dbChirp, err := q.GetChirp(ctx, id)
+				if err != nil {
+					return fmt.Errorf("retrieving chirp %s: %w", id, err)
+				}
+				chirp := chirpResponse(dbChirp)
+				if err := emitWebhookEvent(ctx, q, webhookEventChirpCreated, chirp.UserID, chirp); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "announcing scheduled chirps", "error", err)
+			}
+			return
+		}
+		if claimed < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// runWebhookDeliveries polls the delivery queue until ctx is cancelled,
+// announcing newly published scheduled chirps on every pass. Several
+// instances may run at once; each claims its own batch.
+func (cfg *apiConfig) runWebhookDeliveries(ctx context.Context, client *http.Client) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		cfg.webhookWorkerSeen.Store(time.Now().UnixNano())
+		cfg.announceScheduledChirps(ctx)
+		// A full batch suggests a backlog, so keep draining without waiting.
+		if cfg.deliverDueWebhooks(ctx, client) == webhookBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDueWebhooks claims and attempts one batch of due deliveries and
+// returns how many it claimed.
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context, client *http.Client) int {
+	deliveries, err := cfg.dbQueries.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil:    time.Now().UTC().Add(webhookLease),
+		MaxDeliveries: webhookBatchSize,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "claiming webhook deliveries", "error", err)
+		}
+		return 0
+	}
+
+	for _, d := range deliveries {
+		statusCode, err := sendWebhook(ctx, client, d)
+		if ctx.Err() != nil {
+			// Shutting down: unsent deliveries are retried once their lease
+			// expires, without counting this as a failed attempt.
+			return len(deliveries)
+		}
+		cfg.recordWebhookAttempt(ctx, d, statusCode, err)
+		cfg.webhookWorkerSeen.Store(time.Now().UnixNano())
+	}
+	return len(deliveries)
+}
+
+// sendWebhook POSTs a signed delivery and returns the response status. Any
+// non-2xx status is reported as an error.
+func sendWebhook(ctx context.Context, client *http.Client, d db.ClaimDueWebhookDeliveriesRow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(webhookIDHeader, d.EventID.String())
+	req.Header.Set(webhookEventHeader, d.EventType)
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhookSignatureHeader, webhook.Sign([]byte(d.Secret), now, d.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (cfg *apiConfig) recordWebhookAttempt(ctx context.Context, d db.ClaimDueWebhookDeliveriesRow, statusCode int, deliveryErr error) {
+	attempts := int(d.Attempts) + 1
+	params := db.RecordWebhookDeliveryAttemptParams{
+		ID:             d.ID,
+		Status:         webhookDeliveryStatusSucceeded,
+		NextAttemptAt:  time.Now().UTC(),
+		LastStatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+	}
+	if deliveryErr != nil {
+		params.LastError = sql.NullString{String: deliveryErr.Error(), Valid: true}
+		params.Status = webhookDeliveryStatusPending
+		params.NextAttemptAt = params.NextAttemptAt.Add(webhookRetryDelay(attempts))
+		if attempts >= webhookMaxAttempts {
+			params.Status = webhookDeliveryStatusFailed
+		}
+	}
+
+	if err := cfg.dbQueries.RecordWebhookDeliveryAttempt(ctx, params); err != nil {
+		slog.ErrorContext(ctx, "recording webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// WebhookEndpoint is the JSON representation of a registered endpoint.
+// Secret is only returned when the endpoint is created.
+type WebhookEndpoint struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"events"`
+	AllUsers   bool      `json:"all_users"`
+	Secret     string    `json:"secret,omitempty"`
+}
+
+func webhookEndpointFromDB(e db.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		URL:        e.Url,
+		EventTypes: e.EventTypes,
+		AllUsers:   e.AllUsers,
+	}
+}
+
+// WebhookDelivery is one entry in an endpoint's delivery log.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode *int32          `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+}
+
+func webhookDeliveryFromDB(d db.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		EventID:   d.EventID,
+		EventType: d.EventType,
+		Payload:   d.Payload,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		LastError: d.LastError.String,
+	}
+	if d.Status == webhookDeliveryStatusPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		delivery.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.LastStatusCode.Valid {
+		delivery.LastStatusCode = &d.LastStatusCode.Int32
+	}
+	return delivery
+}
+
+// validWebhookURL requires an absolute https URL, or http during local
+// development.
+func (cfg *apiConfig) validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return false
+	}
+	return u.Scheme == "https" || (u.Scheme == "http" && cfg.platform == config.PlatformDev)
+}
+
+// createWebhookEndpointHandler registers an endpoint for the caller's own
+// events. Admins may set all_users to receive every user's events.
+func (cfg *apiConfig) createWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	type createWebhookEndpointRequest struct {
+		URL      string   `json:"url"`
+		Events   []string `json:"events"`
+		AllUsers bool     `json:"all_users"`
+	}
+
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req createWebhookEndpointRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if !cfg.validWebhookURL(req.URL) {
+		respondWithInvalidField(w, r, "url", problem.Invalid, "URL must be an absolute https URL")
+		return
+	}
+
+	if len(req.Events) == 0 {
+		respondWithInvalidField(w, r, "events", problem.Required, "At least one event is required")
+		return
+	}
+	events := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEventTypes, event) {
+			respondWithInvalidField(w, r, "events", problem.Unknown, fmt.Sprintf("Unknown event %q", event))
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	if req.AllUsers && !principal.Role.AtLeast(auth.RoleAdmin) {
+		respondWithError(w, r, http.StatusForbidden, "Only admins can receive events for all users")
+		return
+	}
+
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "creating webhook secret", "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not create webhook")
+		return
+	}
+
+	endpoint, err := cfg.dbQueries.CreateWebhookEndpoint(r.Context(), db.CreateWebhookEndpointParams{
+		OwnerID:    principal.UserID,
+		Url:        req.URL,
+		Secret:     secret,
+		EventTypes: events,
+		AllUsers:   req.AllUsers,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "creating webhook endpoint", "error", err)
+		respondWithDBError(w, r, err, "Could not create webhook")
+		return
+	}
+
+	response := webhookEndpointFromDB(endpoint)
+	response.Secret = endpoint.Secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) listWebhookEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpoints, err := cfg.dbQueries.ListWebhookEndpointsByOwner(r.Context(), principal.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "listing webhook endpoints", "error", err)
+		respondWithDBError(w, r, err, "Could not list webhooks")
+		return
+	}
+
+	response := make([]WebhookEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		response = append(response, webhookEndpointFromDB(e))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) deleteWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteWebhookEndpoint(r.Context(), db.DeleteWebhookEndpointParams{
+		ID:      endpointID,
+		OwnerID: principal.UserID,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "deleting webhook endpoint", "endpoint_id", endpointID, "error", err)
+		respondWithDBError(w, r, err, "Could not delete webhook")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listWebhookDeliveriesHandler returns the 100 most recent deliveries to one
+// of the caller's endpoints.
+func (cfg *apiConfig) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	endpoint, err := cfg.dbQueries.GetWebhookEndpoint(r.Context(), endpointID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.ErrorContext(r.Context(), "retrieving webhook endpoint", "endpoint_id", endpointID, "error", err)
+		respondWithDBError(w, r, err, "Could not list deliveries")
+		return
+	}
+	if err != nil || endpoint.OwnerID != principal.UserID {
+		respondWithError(w, r, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	deliveries, err := cfg.dbQueries.ListWebhookDeliveriesByEndpoint(r.Context(), db.ListWebhookDeliveriesByEndpointParams{
+		EndpointID: endpointID,
+		Limit:      100,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "listing webhook deliveries", "endpoint_id", endpointID, "error", err)
+		respondWithDBError(w, r, err, "Could not list deliveries")
+		return
+	}
+
+	response := make([]WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		response = append(response, webhookDeliveryFromDB(d))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// redeliverWebhookHandler queues a delivery to be sent again immediately,
+// with a fresh set of retries.
+func (cfg *apiConfig) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := cfg.dbQueries.RedeliverWebhookDelivery(r.Context(), db.RedeliverWebhookDeliveryParams{
+		ID:      deliveryID,
+		OwnerID: principal.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "Delivery not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "redelivering webhook", "delivery_id", deliveryID, "error", err)
+		respondWithDBError(w, r, err, "Could not redeliver webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, webhookDeliveryFromDB(delivery))
+}
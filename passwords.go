@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/problem"
+)
+
+// respondWithPasswordPolicyError reports every violated password rule as a
+// field error on "password", coded by rule, or a generic 400 if err is not a
+// policy error.
+func respondWithPasswordPolicyError(w http.ResponseWriter, r *http.Request, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid password")
+		return
+	}
+
+	errs := make([]problem.FieldError, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		errs = append(errs, problem.FieldError{
+			Field:  "password",
+			Code:   problem.Code(v.Rule),
+			Detail: "Password " + v.Message,
+		})
+	}
+	problem.Write(w, r, problem.ValidationFailed, "Password does not meet requirements", errs...)
+}
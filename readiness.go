@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"chirpy/internal/health"
+	"chirpy/internal/migrate"
+	"chirpy/internal/storage"
+	"github.com/pressly/goose/v3"
+)
+
+const (
+	// readinessTimeout bounds each readiness probe, so a database that
+	// accepts connections but never answers still fails it.
+	readinessTimeout = 2 * time.Second
+	// webhookWorkerStale is how long the webhook delivery worker may go
+	// without progress; sending one delivery takes up to
+	// webhookRequestTimeout.
+	webhookWorkerStale = 3 * (webhookRequestTimeout + webhookPollInterval)
+)
+
+// newReadiness builds the checks behind /api/readyz.
+func (cfg *apiConfig) newReadiness(dbConn *storage.DB) (*health.Checker, error) {
+	provider, err := migrate.NewProvider(dbConn)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := health.NewChecker(readinessTimeout)
+	checker.Add("database", databaseCheck(dbConn))
+	checker.Add("migrations", migrationsCheck(provider))
+	checker.Add("webhook_deliveries", cfg.webhookWorkerCheck)
+	return checker, nil
+}
+
+type poolHealth struct {
+	Backend       string `json:"backend"`
+	MaxConns      int    `json:"max_conns"`
+	TotalConns    int    `json:"total_conns"`
+	IdleConns     int    `json:"idle_conns"`
+	AcquiredConns int    `json:"acquired_conns"`
+	WaitCount     int64  `json:"wait_count"`
+	WaitMillis    int64  `json:"wait_ms"`
+	// Saturated is set while every connection is in use. Queries queue for
+	// a connection rather than fail, so it does not fail the check.
+	Saturated bool `json:"saturated"`
+}
+
+// databaseCheck pings the database and reports its connection pool.
+func databaseCheck(dbConn *storage.DB) health.Check {
+	return func(ctx context.Context) (any, error) {
+		stats := dbConn.PoolStats()
+		pool := poolHealth{
+			Backend:       string(dbConn.Backend),
+			MaxConns:      stats.MaxConns,
+			TotalConns:    stats.TotalConns,
+			IdleConns:     stats.IdleConns,
+			AcquiredConns: stats.AcquiredConns,
+			WaitCount:     stats.WaitCount,
+			WaitMillis:    stats.WaitDuration.Milliseconds(),
+			Saturated:     stats.MaxConns > 0 && stats.AcquiredConns >= stats.MaxConns,
+		}
+		return pool, dbConn.PingContext(ctx)
+	}
+}
+
+// migrationsCheck fails while the database schema is older than the
+// migrations built into the server. A newer schema passes: during a rolling
+// deploy the new release migrates while the old one is still serving, and
+// migrations are written to keep the previous release working.
+func migrationsCheck(provider *goose.Provider) health.Check {
+	type versions struct {
+		Current  int64 `json:"current"`
+		Required int64 `json:"required"`
+	}
+	return func(ctx context.Context) (any, error) {
+		current, required, err := provider.GetVersions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading schema version: %w", err)
+		}
+		v := versions{Current: current, Required: required}
+		if current < required {
+			return v, fmt.Errorf("schema version %d is behind %d; run \"chirpy migrate up\"", current, required)
+		}
+		return v, nil
+	}
+}
+
+// webhookWorkerCheck fails when the webhook delivery worker has stalled.
+func (cfg *apiConfig) webhookWorkerCheck(ctx context.Context) (any, error) {
+	type progress struct {
+		IdleMillis int64 `json:"idle_ms"`
+	}
+	seen := cfg.webhookWorkerSeen.Load()
+	if seen == 0 {
+		return nil, errors.New("delivery worker has not started")
+	}
+	idle := time.Since(time.Unix(0, seen))
+	if idle > webhookWorkerStale {
+		return progress{idle.Milliseconds()}, fmt.Errorf("delivery worker has made no progress for %s", idle.Round(time.Second))
+	}
+	return progress{idle.Milliseconds()}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"chirpy/internal/config"
+)
+
+// newServer builds the HTTP server for handler from the listener settings.
+func newServer(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout.Std(),
+		ReadTimeout:       cfg.ReadTimeout.Std(),
+		WriteTimeout:      cfg.WriteTimeout.Std(),
+		IdleTimeout:       cfg.IdleTimeout.Std(),
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// shutdownOptions controls how serve stops once its context is cancelled.
+type shutdownOptions struct {
+	// Drain is called as soon as shutdown begins, to report the server as
+	// not ready. It may be nil.
+	Drain func()
+	// Delay keeps accepting requests after Drain, giving load balancers time
+	// to notice and stop routing to the server.
+	Delay time.Duration
+	// Timeout bounds how long in-flight requests may take to finish.
+	Timeout time.Duration
+}
+
+// serve runs server on ln until it fails or ctx is cancelled. It then drains,
+// stops accepting connections and waits for in-flight requests, as set by
+// shutdown.
+func serve(ctx context.Context, server *http.Server, ln net.Listener, shutdown shutdownOptions) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", ln.Addr().String())
+		serverErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	if shutdown.Drain != nil {
+		shutdown.Drain()
+	}
+	if shutdown.Delay > 0 {
+		slog.Info("draining", "delay", shutdown.Delay.String())
+		select {
+		case err := <-serverErr:
+			return fmt.Errorf("server error: %w", err)
+		case <-time.After(shutdown.Delay):
+		}
+	}
+
+	slog.Info("shutting down", "timeout", shutdown.Timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Connections still open at the deadline are cut off.
+		_ = server.Close()
+		return fmt.Errorf("shutting down server: %w", err)
+	}
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+-- name: CreateChirpPost :exec
+INSERT INTO chirp_posts (id, user_id, created_at)
+VALUES (gen_random_uuid(), $1, NOW());
+
+-- name: CountChirpPostsSince :one
+SELECT COUNT(*)
+FROM chirp_posts
+WHERE user_id = $1
+  AND created_at >= $2;
+
+-- name: DeleteChirpPostsBefore :exec
+DELETE FROM chirp_posts
+WHERE user_id = $1
+  AND created_at < $2;
+
+-- name: CreateScheduledChirp :exec
+INSERT INTO scheduled_chirps (chirp_id, publish_at)
+VALUES ($1, $2);
+
+-- name: ClaimPublishedChirps :many
+DELETE FROM scheduled_chirps
+WHERE chirp_id IN (
+    SELECT chirp_id
+    FROM scheduled_chirps
+    WHERE publish_at <= NOW()
+    ORDER BY publish_at
+    LIMIT sqlc.arg(max_chirps)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING chirp_id;
@@ -1,30 +1,38 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    COALESCE(sqlc.narg(published_at), NOW())
 )
 RETURNING *;
 
 -- name: ListChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, published_at
 FROM chirps
 ORDER BY created_at ASC;
 
 -- name: ListChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, published_at
 FROM chirps
 WHERE user_id = $1
 ORDER BY created_at ASC;
 
 -- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, published_at
 FROM chirps
 WHERE id = $1;
 
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
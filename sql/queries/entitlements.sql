@@ -0,0 +1,26 @@
+-- name: ListUserEntitlements :many
+SELECT user_id, feature, granted, granted_by, created_at, updated_at
+FROM user_entitlements
+WHERE user_id = $1
+ORDER BY feature ASC;
+
+-- name: UpsertUserEntitlement :one
+INSERT INTO user_entitlements (user_id, feature, granted, granted_by, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id, feature) DO UPDATE
+SET granted = EXCLUDED.granted,
+    granted_by = EXCLUDED.granted_by,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteUserEntitlement :execrows
+DELETE FROM user_entitlements
+WHERE user_id = $1
+  AND feature = $2;
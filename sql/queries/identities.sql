@@ -0,0 +1,38 @@
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, link_user_id, use_cookies, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7
+);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state = $1
+RETURNING state, provider, nonce, code_verifier, link_user_id, use_cookies, created_at, expires_at;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at < NOW();
+
+-- name: GetUserIdentity :one
+SELECT provider, subject, user_id, email, created_at, updated_at
+FROM user_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (provider, subject, user_id, email, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
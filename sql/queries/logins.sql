@@ -0,0 +1,53 @@
+-- name: GetLoginThrottle :one
+SELECT key, failures, locked_until, updated_at
+FROM login_throttles
+WHERE key = $1;
+
+-- name: LockLoginThrottle :one
+-- Creates the throttle if needed and locks its row until the transaction
+-- ends, so that concurrent logins check and count failures one at a time.
+INSERT INTO login_throttles (key, failures, locked_until, updated_at)
+VALUES ($1, 0, NULL, NOW())
+ON CONFLICT (key) DO UPDATE
+SET key = EXCLUDED.key
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, failures, locked_until, updated_at)
+VALUES ($1, 1, NULL, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.updated_at < sqlc.arg(window_start) THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    updated_at = NOW()
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: ForgiveLoginFailure :one
+-- Takes back one counted failure, for an attempt that turned out to succeed.
+UPDATE login_throttles
+SET failures = failures - 1
+WHERE key = $1 AND failures > 0
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: SetLoginLockout :exec
+UPDATE login_throttles
+SET locked_until = $2
+WHERE key = $1;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts (id, created_at, email, user_id, ip_address, user_agent, succeeded, failure_reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
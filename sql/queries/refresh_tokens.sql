@@ -10,8 +10,22 @@ VALUES (
 )
 RETURNING *;
 
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    NULL,
+    $4,
+    $5
+)
+RETURNING *;
+
 -- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope
 FROM refresh_tokens
 WHERE token = $1;
 
@@ -22,15 +36,24 @@ SELECT
     u.updated_at AS user_updated_at,
     u.email AS user_email,
     u.hashed_password AS user_hashed_password,
+    u.role AS user_role,
     r.token,
     r.created_at,
     r.updated_at,
     r.expires_at,
-    r.revoked_at
+    r.revoked_at,
+    r.client_id,
+    r.scope
 FROM refresh_tokens r
 JOIN users u ON u.id = r.user_id
 WHERE r.token = $1;
 
+-- name: RevokeActiveRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL;
+
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
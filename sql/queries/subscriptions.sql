@@ -0,0 +1,21 @@
+-- name: GetSubscription :one
+SELECT user_id, status, current_period_start, current_period_end, created_at, updated_at
+FROM subscriptions
+WHERE user_id = $1;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, status, current_period_start, current_period_end, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET status = EXCLUDED.status,
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end = EXCLUDED.current_period_end,
+    updated_at = NOW()
+RETURNING user_id, status, current_period_start, current_period_end, created_at, updated_at;
@@ -13,21 +13,44 @@ RETURNING *;
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, role
 FROM users
 WHERE email = $1;
 
+-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, role
+FROM users
+WHERE id = $1;
+
+-- name: LockUser :exec
+-- Locks the user's row until the transaction ends, so that per-user checks
+-- such as the chirp rate limit run one at a time.
+SELECT id
+FROM users
+WHERE id = $1
+FOR UPDATE;
+
 -- name: UpdateUser :one
 UPDATE users
 SET email = $2,
     hashed_password = $3,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red;
+RETURNING id, created_at, updated_at, email, hashed_password, role;
 
--- name: UpgradeToChirpyRed :one
+-- name: SetUserRole :one
 UPDATE users
-SET is_chirpy_red = TRUE,
+SET role = $2,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red;
+RETURNING id, created_at, updated_at, email, hashed_password, role;
+
+-- name: CountUsersByRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1;
+
+-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET hashed_password = $2
+WHERE id = $1;
@@ -0,0 +1,51 @@
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), e.id, sqlc.arg(event_id), sqlc.arg(event_type), sqlc.arg(payload), 'pending', 0, NOW()
+FROM webhook_endpoints e
+JOIN users u ON u.id = e.owner_id
+WHERE sqlc.arg(event_type)::text = ANY(e.event_types)
+  AND (e.owner_id = sqlc.arg(user_id) OR (e.all_users AND u.role = 'admin'));
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg(lease_until),
+    updated_at = NOW()
+FROM webhook_endpoints e
+WHERE e.id = d.endpoint_id
+  AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(max_deliveries)
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, e.url, e.secret;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    next_attempt_at = $3,
+    last_attempt_at = NOW(),
+    last_status_code = $4,
+    last_error = $5,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListWebhookDeliveriesByEndpoint :many
+SELECT id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error
+FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW(),
+    updated_at = NOW()
+FROM webhook_endpoints e
+WHERE d.id = $1 AND e.id = d.endpoint_id AND e.owner_id = $2
+RETURNING d.id, d.created_at, d.updated_at, d.endpoint_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error;
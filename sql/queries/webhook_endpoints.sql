@@ -0,0 +1,28 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, created_at, updated_at, owner_id, url, secret, event_types, all_users)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT id, created_at, updated_at, owner_id, url, secret, event_types, all_users
+FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: ListWebhookEndpointsByOwner :many
+SELECT id, created_at, updated_at, owner_id, url, secret, event_types, all_users
+FROM webhook_endpoints
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND owner_id = $2;
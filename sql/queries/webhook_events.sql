@@ -0,0 +1,50 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (source, id, event_type, payload, received_at, attempts)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    1
+)
+ON CONFLICT (source, id) DO UPDATE
+SET attempts = webhook_events.attempts + 1
+RETURNING source, id, event_type, payload, received_at, processed_at, attempts, last_error;
+
+-- name: ClaimWebhookEvent :execrows
+UPDATE webhook_events
+SET processed_at = NOW(),
+    last_error = NULL
+WHERE source = $1 AND id = $2 AND processed_at IS NULL;
+
+-- name: FailWebhookEvent :exec
+UPDATE webhook_events
+SET processed_at = NULL,
+    last_error = $3
+WHERE source = $1 AND id = $2;
+
+-- name: RecordWebhookEventError :exec
+-- Records why a replay failed without touching processed_at, so that an
+-- event that was already processed is not applied again on redelivery.
+UPDATE webhook_events
+SET last_error = $3
+WHERE source = $1 AND id = $2;
+
+-- name: MarkWebhookEventReplayed :exec
+UPDATE webhook_events
+SET processed_at = NOW(),
+    last_error = NULL,
+    attempts = attempts + 1
+WHERE source = $1 AND id = $2;
+
+-- name: GetWebhookEvent :one
+SELECT source, id, event_type, payload, received_at, processed_at, attempts, last_error
+FROM webhook_events
+WHERE source = $1 AND id = $2;
+
+-- name: ListWebhookEvents :many
+SELECT source, id, event_type, payload, received_at, processed_at, attempts, last_error
+FROM webhook_events
+ORDER BY received_at DESC
+LIMIT $1;
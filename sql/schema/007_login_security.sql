@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    failure_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_email_created_at_idx
+    ON login_attempts (email, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS login_throttles;
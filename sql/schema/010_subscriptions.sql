@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL
+        CHECK (status IN ('active', 'past_due', 'canceled', 'downgraded', 'refunded')),
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Existing Chirpy Red members have no recorded period; give them one billing
+-- cycle so their next renewal event takes over.
+INSERT INTO subscriptions (user_id, status, current_period_start, current_period_end, created_at, updated_at)
+SELECT id, 'active', NOW(), NOW() + INTERVAL '30 days', NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users
+SET is_chirpy_red = TRUE
+WHERE id IN (
+    SELECT user_id
+    FROM subscriptions
+    WHERE status IN ('active', 'past_due', 'canceled')
+      AND current_period_end > NOW()
+);
+
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhook_events (
+    source TEXT NOT NULL,
+    id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT,
+    PRIMARY KEY (source, id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_received_at_idx ON webhook_events (received_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_events;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_entitlements (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feature TEXT NOT NULL,
+    granted BOOLEAN NOT NULL,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, feature)
+);
+
+-- Scheduled chirps stay hidden from other users until published_at.
+ALTER TABLE chirps ADD COLUMN published_at TIMESTAMP;
+UPDATE chirps SET published_at = created_at;
+ALTER TABLE chirps ALTER COLUMN published_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_id_created_at_idx;
+ALTER TABLE chirps DROP COLUMN published_at;
+DROP TABLE IF EXISTS user_entitlements;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- Every chirp an author posts, kept for the rate limit so that deleting
+-- chirps does not hand back quota. Rows older than the rate window are pruned.
+CREATE TABLE IF NOT EXISTS chirp_posts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chirp_posts_user_id_created_at_idx ON chirp_posts (user_id, created_at);
+
+INSERT INTO chirp_posts (id, user_id, created_at)
+SELECT gen_random_uuid(), user_id, created_at
+FROM chirps
+WHERE created_at >= NOW() - INTERVAL '1 hour';
+
+-- Scheduled chirps waiting for their chirp.created webhook event, which is
+-- queued once publish_at has passed.
+CREATE TABLE IF NOT EXISTS scheduled_chirps (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    publish_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS scheduled_chirps;
+DROP TABLE IF EXISTS chirp_posts;
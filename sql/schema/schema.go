@@ -0,0 +1,10 @@
+// Package schema embeds the goose migrations in this directory so the chirpy
+// binary can apply them itself.
+package schema
+
+import "embed"
+
+// FS holds the migrations, named <version>_<description>.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,30 @@
+-- name: CreateChirpPost :exec
+INSERT INTO chirp_posts (id, user_id, created_at)
+VALUES (sqlc.arg(id), sqlc.arg(user_id), sqlc.arg(now));
+
+-- name: CountChirpPostsSince :one
+SELECT COUNT(*)
+FROM chirp_posts
+WHERE user_id = ?
+  AND created_at >= ?;
+
+-- name: DeleteChirpPostsBefore :exec
+DELETE FROM chirp_posts
+WHERE user_id = ?
+  AND created_at < ?;
+
+-- name: CreateScheduledChirp :exec
+INSERT INTO scheduled_chirps (chirp_id, publish_at)
+VALUES (?, ?);
+
+-- name: ClaimPublishedChirps :many
+-- SQLite serializes writers, so the claim needs no row locks.
+DELETE FROM scheduled_chirps
+WHERE chirp_id IN (
+    SELECT chirp_id
+    FROM scheduled_chirps
+    WHERE publish_at <= sqlc.arg(now)
+    ORDER BY publish_at
+    LIMIT sqlc.arg(max_chirps)
+)
+RETURNING chirp_id;
@@ -0,0 +1,38 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published_at)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(body),
+    sqlc.arg(user_id),
+    COALESCE(sqlc.narg(published_at), sqlc.arg(now))
+)
+RETURNING id, created_at, updated_at, body, user_id, published_at;
+
+-- name: ListChirps :many
+SELECT id, created_at, updated_at, body, user_id, published_at
+FROM chirps
+ORDER BY created_at ASC;
+
+-- name: ListChirpsByAuthor :many
+SELECT id, created_at, updated_at, body, user_id, published_at
+FROM chirps
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, published_at
+FROM chirps
+WHERE id = ?;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = sqlc.arg(body),
+    updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+RETURNING id, created_at, updated_at, body, user_id, published_at;
+
+-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = ?;
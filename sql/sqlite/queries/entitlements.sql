@@ -0,0 +1,26 @@
+-- name: ListUserEntitlements :many
+SELECT user_id, feature, granted, granted_by, created_at, updated_at
+FROM user_entitlements
+WHERE user_id = ?
+ORDER BY feature ASC;
+
+-- name: UpsertUserEntitlement :one
+INSERT INTO user_entitlements (user_id, feature, granted, granted_by, created_at, updated_at)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(feature),
+    sqlc.arg(granted),
+    sqlc.narg(granted_by),
+    sqlc.arg(now),
+    sqlc.arg(now)
+)
+ON CONFLICT (user_id, feature) DO UPDATE
+SET granted = excluded.granted,
+    granted_by = excluded.granted_by,
+    updated_at = excluded.updated_at
+RETURNING user_id, feature, granted, granted_by, created_at, updated_at;
+
+-- name: DeleteUserEntitlement :execrows
+DELETE FROM user_entitlements
+WHERE user_id = ?
+  AND feature = ?;
@@ -0,0 +1,38 @@
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, link_user_id, use_cookies, created_at, expires_at)
+VALUES (
+    sqlc.arg(state),
+    sqlc.arg(provider),
+    sqlc.arg(nonce),
+    sqlc.arg(code_verifier),
+    sqlc.narg(link_user_id),
+    sqlc.arg(use_cookies),
+    sqlc.arg(now),
+    sqlc.arg(expires_at)
+);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_states
+WHERE state = ?
+RETURNING state, provider, nonce, code_verifier, link_user_id, use_cookies, created_at, expires_at;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at < sqlc.arg(now);
+
+-- name: GetUserIdentity :one
+SELECT provider, subject, user_id, email, created_at, updated_at
+FROM user_identities
+WHERE provider = ? AND subject = ?;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (provider, subject, user_id, email, created_at, updated_at)
+VALUES (
+    sqlc.arg(provider),
+    sqlc.arg(subject),
+    sqlc.arg(user_id),
+    sqlc.arg(email),
+    sqlc.arg(now),
+    sqlc.arg(now)
+)
+RETURNING provider, subject, user_id, email, created_at, updated_at;
@@ -0,0 +1,54 @@
+-- name: GetLoginThrottle :one
+SELECT key, failures, locked_until, updated_at
+FROM login_throttles
+WHERE key = ?;
+
+-- name: LockLoginThrottle :one
+-- Creates the throttle if needed. The write holds SQLite's write lock until
+-- the transaction ends, so concurrent logins check and count failures one
+-- at a time.
+INSERT INTO login_throttles (key, failures, locked_until, updated_at)
+VALUES (sqlc.arg(key), 0, NULL, sqlc.arg(now))
+ON CONFLICT (key) DO UPDATE
+SET key = excluded.key
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, failures, locked_until, updated_at)
+VALUES (sqlc.arg(key), 1, NULL, sqlc.arg(now))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.updated_at < sqlc.arg(window_start) THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    updated_at = excluded.updated_at
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: ForgiveLoginFailure :one
+-- Takes back one counted failure, for an attempt that turned out to succeed.
+UPDATE login_throttles
+SET failures = failures - 1
+WHERE key = ? AND failures > 0
+RETURNING key, failures, locked_until, updated_at;
+
+-- name: SetLoginLockout :exec
+UPDATE login_throttles
+SET locked_until = ?
+WHERE key = ?;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = ?;
+
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts (id, created_at, email, user_id, ip_address, user_agent, succeeded, failure_reason)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(now),
+    sqlc.arg(email),
+    sqlc.narg(user_id),
+    sqlc.arg(ip_address),
+    sqlc.arg(user_agent),
+    sqlc.arg(succeeded),
+    sqlc.narg(failure_reason)
+);
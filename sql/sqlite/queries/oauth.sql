@@ -0,0 +1,50 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris, scopes)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(owner_id),
+    sqlc.arg(name),
+    sqlc.narg(secret_hash),
+    sqlc.arg(redirect_uris),
+    sqlc.arg(scopes)
+)
+RETURNING id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris, scopes;
+
+-- name: GetOAuthClient :one
+SELECT id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris, scopes
+FROM oauth_clients
+WHERE id = ?;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris, scopes
+FROM oauth_clients
+WHERE owner_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = ? AND owner_id = ?;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, code_challenge, created_at, expires_at)
+VALUES (
+    sqlc.arg(code_hash),
+    sqlc.arg(client_id),
+    sqlc.arg(user_id),
+    sqlc.arg(redirect_uri),
+    sqlc.arg(scope),
+    sqlc.arg(code_challenge),
+    sqlc.arg(now),
+    sqlc.arg(expires_at)
+);
+
+-- name: ConsumeOAuthAuthorizationCode :one
+DELETE FROM oauth_authorization_codes
+WHERE code_hash = ?
+RETURNING code_hash, client_id, user_id, redirect_uri, scope, code_challenge, created_at, expires_at;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < sqlc.arg(now);
@@ -0,0 +1,61 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+VALUES (
+    sqlc.arg(token),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(user_id),
+    sqlc.arg(expires_at),
+    NULL
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope)
+VALUES (
+    sqlc.arg(token),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(user_id),
+    sqlc.arg(expires_at),
+    NULL,
+    sqlc.narg(client_id),
+    sqlc.narg(scope)
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope;
+
+-- name: GetRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scope
+FROM refresh_tokens
+WHERE token = ?;
+
+-- name: GetUserFromRefreshToken :one
+SELECT
+    u.id AS user_id,
+    u.created_at AS user_created_at,
+    u.updated_at AS user_updated_at,
+    u.email AS user_email,
+    u.hashed_password AS user_hashed_password,
+    u.role AS user_role,
+    r.token,
+    r.created_at,
+    r.updated_at,
+    r.expires_at,
+    r.revoked_at,
+    r.client_id,
+    r.scope
+FROM refresh_tokens r
+JOIN users u ON u.id = r.user_id
+WHERE r.token = ?;
+
+-- name: RevokeActiveRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(now),
+    updated_at = sqlc.arg(now)
+WHERE token = sqlc.arg(token) AND revoked_at IS NULL;
+
+-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(now),
+    updated_at = sqlc.arg(now)
+WHERE token = sqlc.arg(token);
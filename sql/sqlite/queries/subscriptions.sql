@@ -0,0 +1,21 @@
+-- name: GetSubscription :one
+SELECT user_id, status, current_period_start, current_period_end, created_at, updated_at
+FROM subscriptions
+WHERE user_id = ?;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, status, current_period_start, current_period_end, created_at, updated_at)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(status),
+    sqlc.arg(current_period_start),
+    sqlc.arg(current_period_end),
+    sqlc.arg(now),
+    sqlc.arg(now)
+)
+ON CONFLICT (user_id) DO UPDATE
+SET status = excluded.status,
+    current_period_start = excluded.current_period_start,
+    current_period_end = excluded.current_period_end,
+    updated_at = excluded.updated_at
+RETURNING user_id, status, current_period_start, current_period_end, created_at, updated_at;
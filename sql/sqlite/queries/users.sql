@@ -0,0 +1,56 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(email),
+    sqlc.arg(hashed_password)
+)
+RETURNING id, created_at, updated_at, email, hashed_password, role;
+
+-- name: DeleteUsers :exec
+DELETE FROM users;
+
+-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, email, hashed_password, role
+FROM users
+WHERE email = ?;
+
+-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, role
+FROM users
+WHERE id = ?;
+
+-- name: LockUser :exec
+-- SQLite has no row locks. The write takes its database write lock until
+-- the transaction ends, so that per-user checks such as the chirp rate limit
+-- run one at a time.
+UPDATE users
+SET id = id
+WHERE id = ?;
+
+-- name: UpdateUser :one
+UPDATE users
+SET email = sqlc.arg(email),
+    hashed_password = sqlc.arg(hashed_password),
+    updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+RETURNING id, created_at, updated_at, email, hashed_password, role;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = sqlc.arg(role),
+    updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+RETURNING id, created_at, updated_at, email, hashed_password, role;
+
+-- name: CountUsersByRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = ?;
+
+-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET hashed_password = ?
+WHERE id = ?;
@@ -0,0 +1,60 @@
+-- name: EnqueueWebhookDeliveries :execrows
+-- Delivery IDs come from SQLite's randomblob, formatted like a version 4 UUID.
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at)
+SELECT
+    lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-'
+        || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+    sqlc.arg(now), sqlc.arg(now), e.id, sqlc.arg(event_id), sqlc.arg(event_type), CAST(sqlc.arg(payload) AS BLOB), 'pending', 0, sqlc.arg(now)
+FROM webhook_endpoints e
+JOIN users u ON u.id = e.owner_id
+WHERE EXISTS (SELECT 1 FROM json_each(e.event_types) WHERE json_each.value = CAST(sqlc.arg(event_type) AS TEXT))
+  AND (e.owner_id = sqlc.arg(user_id) OR (e.all_users AND u.role = 'admin'));
+
+-- name: ClaimDueWebhookDeliveries :many
+-- SQLite serializes writers, so the lease needs no row locks.
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until),
+    updated_at = sqlc.arg(now)
+WHERE id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= sqlc.arg(now)
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(max_deliveries)
+)
+RETURNING
+    id,
+    event_id,
+    event_type,
+    payload,
+    attempts,
+    (SELECT url FROM webhook_endpoints e WHERE e.id = endpoint_id) AS url,
+    (SELECT secret FROM webhook_endpoints e WHERE e.id = endpoint_id) AS secret;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_attempt_at = sqlc.arg(now),
+    last_status_code = sqlc.narg(last_status_code),
+    last_error = sqlc.narg(last_error),
+    updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id);
+
+-- name: ListWebhookDeliveriesByEndpoint :many
+SELECT id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error
+FROM webhook_deliveries
+WHERE endpoint_id = ?
+ORDER BY created_at DESC
+LIMIT ?;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = sqlc.arg(now),
+    updated_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id)
+  AND endpoint_id IN (SELECT e.id FROM webhook_endpoints e WHERE e.owner_id = sqlc.arg(owner_id))
+RETURNING id, created_at, updated_at, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error;
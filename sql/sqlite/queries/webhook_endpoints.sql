@@ -0,0 +1,28 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, created_at, updated_at, owner_id, url, secret, event_types, all_users)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(now),
+    sqlc.arg(now),
+    sqlc.arg(owner_id),
+    sqlc.arg(url),
+    sqlc.arg(secret),
+    sqlc.arg(event_types),
+    sqlc.arg(all_users)
+)
+RETURNING id, created_at, updated_at, owner_id, url, secret, event_types, all_users;
+
+-- name: GetWebhookEndpoint :one
+SELECT id, created_at, updated_at, owner_id, url, secret, event_types, all_users
+FROM webhook_endpoints
+WHERE id = ?;
+
+-- name: ListWebhookEndpointsByOwner :many
+SELECT id, created_at, updated_at, owner_id, url, secret, event_types, all_users
+FROM webhook_endpoints
+WHERE owner_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = ? AND owner_id = ?;
@@ -0,0 +1,50 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (source, id, event_type, payload, received_at, attempts)
+VALUES (
+    sqlc.arg(source),
+    sqlc.arg(id),
+    sqlc.arg(event_type),
+    sqlc.arg(payload),
+    sqlc.arg(now),
+    1
+)
+ON CONFLICT (source, id) DO UPDATE
+SET attempts = webhook_events.attempts + 1
+RETURNING source, id, event_type, payload, received_at, processed_at, attempts, last_error;
+
+-- name: ClaimWebhookEvent :execrows
+UPDATE webhook_events
+SET processed_at = sqlc.arg(now),
+    last_error = NULL
+WHERE source = sqlc.arg(source) AND id = sqlc.arg(id) AND processed_at IS NULL;
+
+-- name: FailWebhookEvent :exec
+UPDATE webhook_events
+SET processed_at = NULL,
+    last_error = ?
+WHERE source = ? AND id = ?;
+
+-- name: RecordWebhookEventError :exec
+-- Records why a replay failed without touching processed_at, so that an
+-- event that was already processed is not applied again on redelivery.
+UPDATE webhook_events
+SET last_error = ?
+WHERE source = ? AND id = ?;
+
+-- name: MarkWebhookEventReplayed :exec
+UPDATE webhook_events
+SET processed_at = sqlc.arg(now),
+    last_error = NULL,
+    attempts = attempts + 1
+WHERE source = sqlc.arg(source) AND id = sqlc.arg(id);
+
+-- name: GetWebhookEvent :one
+SELECT source, id, event_type, payload, received_at, processed_at, attempts, last_error
+FROM webhook_events
+WHERE source = ? AND id = ?;
+
+-- name: ListWebhookEvents :many
+SELECT source, id, event_type, payload, received_at, processed_at, attempts, last_error
+FROM webhook_events
+ORDER BY received_at DESC
+LIMIT ?;
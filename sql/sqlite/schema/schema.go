@@ -0,0 +1,10 @@
+// Package schema embeds the goose migrations for the SQLite backend, which
+// start from the current PostgreSQL schema rather than replaying its history.
+package schema
+
+import "embed"
+
+// FS holds the migrations, named <version>_<description>.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/auth"
+	db "chirpy/internal/database"
+	"chirpy/internal/problem"
+	"chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// Polka webhook events that change a Chirpy Red subscription.
+const (
+	polkaEventUpgraded      = "user.upgraded"
+	polkaEventRenewed       = "user.renewed"
+	polkaEventPaymentFailed = "user.payment_failed"
+	polkaEventCancelled     = "user.cancelled"
+	polkaEventDowngraded    = "user.downgraded"
+	polkaEventRefunded      = "user.refunded"
+)
+
+// Subscription statuses stored in subscriptions.status.
+const (
+	subscriptionActive     = "active"
+	subscriptionPastDue    = "past_due"
+	subscriptionCanceled   = "canceled"
+	subscriptionDowngraded = "downgraded"
+	subscriptionRefunded   = "refunded"
+)
+
+// chirpyRedPeriod is the billing period assumed when Polka does not send one.
+const chirpyRedPeriod = 30 * 24 * time.Hour
+
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID      uuid.UUID  `json:"user_id"`
+		PeriodStart *time.Time `json:"period_start"`
+		PeriodEnd   *time.Time `json:"period_end"`
+	} `json:"data"`
+}
+
+// hasChirpyRed reports whether sub grants Chirpy Red at now. Cancelled and
+// past-due subscriptions keep Red until the end of the period already paid
+// for; downgrades and refunds end it immediately.
+func hasChirpyRed(sub db.Subscription, now time.Time) bool {
+	switch sub.Status {
+	case subscriptionActive, subscriptionPastDue, subscriptionCanceled:
+		return now.Before(sub.CurrentPeriodEnd)
+	default:
+		return false
+	}
+}
+
+// nextSubscription applies a Polka event to the user's current subscription,
+// if any. It returns false when the event does not change anything, such as
+// an unknown event or a cancellation for a user who never subscribed.
+func nextSubscription(current *db.Subscription, event polkaEvent, now time.Time) (db.UpsertSubscriptionParams, bool) {
+	next := db.UpsertSubscriptionParams{
+		UserID: event.Data.UserID,
+		Status: subscriptionActive,
+	}
+
+	switch event.Event {
+	case polkaEventUpgraded, polkaEventRenewed:
+		next.CurrentPeriodStart = now
+		// Renewals extend a live period rather than restarting it.
+		if event.Event == polkaEventRenewed && current != nil && hasChirpyRed(*current, now) {
+			next.CurrentPeriodStart = current.CurrentPeriodEnd
+		}
+		if event.Data.PeriodStart != nil {
+			next.CurrentPeriodStart = event.Data.PeriodStart.UTC()
+		}
+		next.CurrentPeriodEnd = next.CurrentPeriodStart.Add(chirpyRedPeriod)
+		if event.Data.PeriodEnd != nil {
+			next.CurrentPeriodEnd = event.Data.PeriodEnd.UTC()
+		}
+		return next, true
+	}
+
+	if current == nil {
+		return db.UpsertSubscriptionParams{}, false
+	}
+	next.CurrentPeriodStart = current.CurrentPeriodStart
+	next.CurrentPeriodEnd = current.CurrentPeriodEnd
+
+	switch event.Event {
+	case polkaEventPaymentFailed:
+		next.Status = subscriptionPastDue
+	case polkaEventCancelled:
+		next.Status = subscriptionCanceled
+	case polkaEventDowngraded, polkaEventRefunded:
+		next.Status = subscriptionDowngraded
+		if event.Event == polkaEventRefunded {
+			next.Status = subscriptionRefunded
+		}
+		if now.Before(next.CurrentPeriodEnd) {
+			next.CurrentPeriodEnd = now
+		}
+	default:
+		return db.UpsertSubscriptionParams{}, false
+	}
+	return next, true
+}
+
+// polkaWebhookSource identifies Polka deliveries in webhook_events.
+const polkaWebhookSource = "polka"
+
+// Headers carrying Polka's webhook signature, see package webhook.
+const (
+	polkaTimestampHeader = "X-Polka-Timestamp"
+	polkaSignatureHeader = "X-Polka-Signature"
+)
+
+var errPolkaUserNotFound = errors.New("user not found")
+
+// polkaWebhookHandler records Chirpy Red subscription changes sent by Polka.
+// Every delivery is stored in webhook_events keyed by its event ID, so
+// redeliveries are acknowledged without being applied twice. Unknown events
+// are acknowledged so that Polka does not retry them.
+func (cfg *apiConfig) polkaWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		problem.Write(w, r, problem.InvalidBody, "")
+		return
+	}
+
+	if !cfg.authenticatePolka(r, body) {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var event polkaEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		problem.Write(w, r, problem.InvalidBody, "")
+		return
+	}
+
+	eventID := event.ID
+	if eventID == "" {
+		eventID = cfg.polkaDeliveryID(r, body)
+	}
+
+	if _, err := cfg.dbQueries.RecordWebhookEvent(r.Context(), db.RecordWebhookEventParams{
+		Source:    polkaWebhookSource,
+		ID:        eventID,
+		EventType: event.Event,
+		Payload:   body,
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "recording polka event", "event_id", eventID, "error", err)
+		respondWithDBError(w, r, err, "Could not record event")
+		return
+	}
+
+	// The claim commits only with the event's effects, so an event that
+	// fails to apply stays unprocessed and its redelivery is applied, while
+	// a concurrent redelivery waits for the claim and then skips it.
+	err = cfg.tx.InTx(r.Context(), func(q db.Querier) error {
+		claimed, err := q.ClaimWebhookEvent(r.Context(), db.ClaimWebhookEventParams{
+			Source: polkaWebhookSource,
+			ID:     eventID,
+		})
+		if err != nil || claimed == 0 {
+			return err
+		}
+		return cfg.applyPolkaEvent(r.Context(), q, event)
+	})
+	if err != nil {
+		if failErr := cfg.dbQueries.FailWebhookEvent(r.Context(), db.FailWebhookEventParams{
+			Source:    polkaWebhookSource,
+			ID:        eventID,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		}); failErr != nil {
+			slog.ErrorContext(r.Context(), "recording polka event failure", "event_id", eventID, "error", failErr)
+		}
+
+		if errors.Is(err, errPolkaUserNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "applying polka event", "event_id", eventID, "error", err)
+		respondWithDBError(w, r, err, "Could not update subscription")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// polkaDeliveryID keys a delivery whose payload has no event ID. Such
+// payloads are identical for every event of one type and user, e.g. an
+// upgrade after a downgrade, so the body alone cannot tell events apart.
+// A signed delivery is keyed by its body and signed timestamp, which Polka
+// keeps when it retries; a delivery authenticated by API key cannot be told
+// from a new event and gets an ID of its own.
+func (cfg *apiConfig) polkaDeliveryID(r *http.Request, body []byte) string {
+	if len(cfg.polkaWebhookSecret) == 0 {
+		return "delivery:" + uuid.NewString()
+	}
+	return "sha256:" + auth.HashToken(r.Header.Get(polkaTimestampHeader)+"."+string(body))
+}
+
+// authenticatePolka verifies the delivery's HMAC signature when a webhook
+// secret is configured, and otherwise falls back to the legacy static API key.
+func (cfg *apiConfig) authenticatePolka(r *http.Request, body []byte) bool {
+	if len(cfg.polkaWebhookSecret) > 0 {
+		err := webhook.Verify(cfg.polkaWebhookSecret,
+			r.Header.Get(polkaTimestampHeader),
+			r.Header.Get(polkaSignatureHeader),
+			body, time.Now(), webhook.DefaultTolerance)
+		return err == nil
+	}
+
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil || cfg.polkaKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.polkaKey)) == 1
+}
+
+// applyPolkaEvent updates the user's subscription for event using q.
+func (cfg *apiConfig) applyPolkaEvent(ctx context.Context, q db.Querier, event polkaEvent) error {
+	var current *db.Subscription
+	sub, err := q.GetSubscription(ctx, event.Data.UserID)
+	switch {
+	case err == nil:
+		current = &sub
+	case !errors.Is(err, db.ErrNotFound):
+		return fmt.Errorf("retrieving subscription: %w", err)
+	}
+
+	next, ok := nextSubscription(current, event, time.Now().UTC())
+	if !ok {
+		return nil
+	}
+
+	updated, err := q.UpsertSubscription(ctx, next)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return errPolkaUserNotFound
+		}
+		return fmt.Errorf("updating subscription: %w", err)
+	}
+
+	now := time.Now().UTC()
+	wasRed := current != nil && hasChirpyRed(*current, now)
+	isRed := hasChirpyRed(updated, now)
+	if wasRed != isRed {
+		eventType := webhookEventUserUpgraded
+		if !isRed {
+			eventType = webhookEventUserDowngraded
+		}
+		return emitWebhookEvent(ctx, q, eventType, updated.UserID, subscriptionWebhookData{
+			UserID:           updated.UserID,
+			IsChirpyRed:      isRed,
+			Status:           updated.Status,
+			CurrentPeriodEnd: updated.CurrentPeriodEnd,
+		})
+	}
+	return nil
+}
+
+// subscriptionWebhookData is the data of user.upgraded and user.downgraded
+// webhook events.
+type subscriptionWebhookData struct {
+	UserID           uuid.UUID `json:"user_id"`
+	IsChirpyRed      bool      `json:"is_chirpy_red"`
+	Status           string    `json:"status"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+}
+
+// WebhookEvent is the admin view of a received webhook delivery.
+type WebhookEvent struct {
+	Source      string          `json:"source"`
+	ID          string          `json:"id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+	Attempts    int32           `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+func webhookEventFromDB(e db.WebhookEvent) WebhookEvent {
+	event := WebhookEvent{
+		Source:     e.Source,
+		ID:         e.ID,
+		EventType:  e.EventType,
+		Payload:    e.Payload,
+		ReceivedAt: e.ReceivedAt,
+		Attempts:   e.Attempts,
+		LastError:  e.LastError.String,
+	}
+	if e.ProcessedAt.Valid {
+		event.ProcessedAt = &e.ProcessedAt.Time
+	}
+	return event
+}
+
+// listWebhookEventsHandler returns the most recently received webhook events,
+// newest first. ?limit= caps the result at 500.
+func (cfg *apiConfig) listWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 500 {
+			respondWithInvalidField(w, r, "limit", problem.Invalid, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+
+	events, err := cfg.dbQueries.ListWebhookEvents(r.Context(), int32(limit))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "listing webhook events", "error", err)
+		respondWithDBError(w, r, err, "Could not list webhook events")
+		return
+	}
+
+	response := make([]WebhookEvent, 0, len(events))
+	for _, e := range events {
+		response = append(response, webhookEventFromDB(e))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// replayWebhookEventHandler applies a stored event again, whether or not it
+// was processed before. It is meant for recovering from failures; replaying
+// a renewal that already succeeded extends the subscription a second time.
+func (cfg *apiConfig) replayWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	key := db.GetWebhookEventParams{
+		Source: r.PathValue("source"),
+		ID:     r.PathValue("eventID"),
+	}
+	if key.Source != polkaWebhookSource {
+		respondWithError(w, r, http.StatusNotFound, "Webhook event not found")
+		return
+	}
+
+	stored, err := cfg.dbQueries.GetWebhookEvent(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondWithError(w, r, http.StatusNotFound, "Webhook event not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "retrieving webhook event", "event_id", key.ID, "error", err)
+		respondWithDBError(w, r, err, "Could not replay webhook event")
+		return
+	}
+
+	var event polkaEvent
+	if err := json.Unmarshal(stored.Payload, &event); err != nil {
+		slog.ErrorContext(r.Context(), "decoding stored webhook event", "event_id", key.ID, "error", err)
+		respondWithError(w, r, http.StatusInternalServerError, "Could not replay webhook event")
+		return
+	}
+
+	err = cfg.tx.InTx(r.Context(), func(q db.Querier) error {
+		if err := cfg.applyPolkaEvent(r.Context(), q, event); err != nil {
+			return err
+		}
+		return q.MarkWebhookEventReplayed(r.Context(), db.MarkWebhookEventReplayedParams(key))
+	})
+	if err != nil {
+		// processed_at is left alone: an event that was processed before must
+		// not be applied again when Polka redelivers it.
+		if failErr := cfg.dbQueries.RecordWebhookEventError(r.Context(), db.RecordWebhookEventErrorParams{
+			Source:    key.Source,
+			ID:        key.ID,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		}); failErr != nil {
+			slog.ErrorContext(r.Context(), "recording webhook event failure", "event_id", key.ID, "error", failErr)
+		}
+		slog.ErrorContext(r.Context(), "replaying webhook event", "event_id", key.ID, "error", err)
+		respondWithError(w, r, http.StatusUnprocessableEntity, "Replay failed: "+err.Error())
+		return
+	}
+
+	stored, err = cfg.dbQueries.GetWebhookEvent(r.Context(), key)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "retrieving webhook event", "event_id", key.ID, "error", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, webhookEventFromDB(stored))
+}
+
+// isChirpyRed reports whether the user currently has Chirpy Red.
+func (cfg *apiConfig) isChirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	sub, err := cfg.dbQueries.GetSubscription(ctx, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return hasChirpyRed(sub, time.Now().UTC()), nil
+}
+
+// userResponse builds the public JSON representation of dbUser.
+func (cfg *apiConfig) userResponse(ctx context.Context, dbUser db.User) (User, error) {
+	isChirpyRed, err := cfg.isChirpyRed(ctx, dbUser.ID)
+	if err != nil {
+		return User{}, err
+	}
+
+	return User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		IsChirpyRed: isChirpyRed,
+		Role:        dbUser.Role,
+	}, nil
+}